@@ -0,0 +1,370 @@
+package pcache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pcache/singleflight"
+)
+
+// BatchGetter 是 Getter 可选实现的接口，一次从数据源获取多个 key，例如使用 IN (...) 查询
+// 返回的 map 中不存在的 key 视为 ErrNotFound，返回错误表示整批获取失败
+// 通过 GetBatch 获取的值没有单独的过期时间，使用 Group 的默认 TTL，不会调用 ExpireGetter
+type BatchGetter interface {
+	GetBatch(ctx context.Context, keys []string) (map[string][]byte, error)
+}
+
+// BatchGetterFunc 实现了 Getter、ContextGetter 和 BatchGetter 接口
+type BatchGetterFunc func(ctx context.Context, keys []string) (map[string][]byte, error)
+
+func (f BatchGetterFunc) Get(key string) ([]byte, error) {
+	return f.GetContext(context.Background(), key)
+}
+
+func (f BatchGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	values, err := f(ctx, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	value, ok := values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+func (f BatchGetterFunc) GetBatch(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return f(ctx, keys)
+}
+
+// Result 是 GetMany 中一个 key 的结果
+type Result struct {
+	Value ByteView
+	Err   error
+}
+
+// GetMany 批量获取 keys 对应的值，返回的结果与 keys 一一对应
+// 未命中缓存的 key 按所属节点分组，每个远程节点只发送一次请求；
+// 当前节点负责的 key 在 getter 实现了 BatchGetter 时一次从数据源获取
+func (g *Group) GetMany(ctx context.Context, keys []string) []Result {
+	results := make([]Result, len(keys))
+	// pending 记录需要加载的 key 在 keys 中的下标，重复的 key 只加载一次
+	pending := make(map[string][]int)
+	var missed []string
+	for i, key := range keys {
+		g.stats.gets.Add(1)
+		if key == "" {
+			results[i].Err = ErrKeyRequired
+			continue
+		}
+		if idx, ok := pending[key]; ok {
+			pending[key] = append(idx, i)
+			continue
+		}
+		if v, ok := g.lookupCache(key); ok {
+			g.stats.hits.Add(1)
+			results[i].Value = v
+			continue
+		}
+		if g.missCache != nil {
+			if _, ok := g.missCache.get(key); ok {
+				g.stats.negativeHits.Add(1)
+				results[i].Err = ErrNotFound
+				continue
+			}
+		}
+		g.stats.misses.Add(1)
+		pending[key] = []int{i}
+		missed = append(missed, key)
+	}
+	if len(missed) == 0 {
+		return results
+	}
+
+	loaded := g.loadMany(ctx, missed)
+	for key, idx := range pending {
+		for _, i := range idx {
+			results[i] = loaded[key]
+		}
+	}
+	return results
+}
+
+// loadMany 加载 keys，每个 key 都通过 flight 加载，与并发的 Get 和 GetMany 共享结果
+// 由当前调用方发起加载的 key 交给 loadOwned 批量加载，其余的 key 等待已有的加载
+func (g *Group) loadMany(ctx context.Context, keys []string) map[string]Result {
+	loaded := make(map[string]Result, len(keys))
+	if err := ctx.Err(); err != nil {
+		for _, key := range keys {
+			loaded[key] = Result{Err: err}
+		}
+		return loaded
+	}
+
+	m := &manyLoad{results: make(map[string]chan Result, len(keys))}
+	m.ctx, m.cancel = context.WithTimeout(singleflight.WithoutCancel(ctx), g.timeout)
+	m.waiting.Store(int64(len(keys)))
+	calls := make([]*singleflight.Call, len(keys))
+	var owned []string
+	for i, key := range keys {
+		ch := make(chan Result, 1)
+		m.results[key] = ch
+		calls[i] = g.flight.Join(ctx, key, func(ctx context.Context) (interface{}, error) {
+			return m.wait(ctx, ch)
+		})
+		if calls[i].Leader() {
+			owned = append(owned, key)
+		} else {
+			g.stats.dedups.Add(1)
+		}
+	}
+	m.release(int64(len(keys) - len(owned)))
+	if len(owned) > 0 {
+		go m.run(func(set func(string, Result)) {
+			g.loadOwned(m.ctx, owned, set)
+		})
+	} else {
+		m.cancel()
+	}
+
+	for i, key := range keys {
+		view, err, _ := calls[i].Wait(ctx)
+		if err != nil {
+			loaded[key] = Result{Err: err}
+		} else {
+			loaded[key] = Result{Value: view.(ByteView)}
+		}
+	}
+	return loaded
+}
+
+// manyLoad 是 GetMany 中由当前调用方发起加载的一批 key
+// 每个 key 在 flight 中的 fn 等待 manyLoad 给出的结果，所有 key 的调用方都放弃之后取消整批加载
+type manyLoad struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	results map[string]chan Result // results 在创建后只读，每个 channel 只写入一次
+	waiting atomic.Int64           // waiting 是还有调用方在等待的 key 的数量
+	panic   interface{}            // panic 是加载时 panic 的值，在写入 results 之前设置
+}
+
+// wait 等待 key 的结果，ctx 是 flight 中 fn 的 ctx，它在 key 的所有调用方都放弃之后结束
+func (m *manyLoad) wait(ctx context.Context, ch chan Result) (interface{}, error) {
+	select {
+	case r := <-ch:
+		if r.Err == errBatchAborted && m.panic != nil {
+			// 在 flight 中 panic，使等待 key 的调用方同样 panic
+			panic(m.panic)
+		}
+		return r.Value, r.Err
+	case <-ctx.Done():
+		m.release(1)
+		return nil, ctx.Err()
+	}
+}
+
+// release 减少等待的 key 的数量，减少到 0 时取消整批加载
+func (m *manyLoad) release(n int64) {
+	if m.waiting.Add(-n) == 0 {
+		m.cancel()
+	}
+}
+
+// run 执行 load，load 通过 set 给出每个 key 的结果
+// load 结束后没有结果的 key 返回错误，保证等待它们的调用方不会一直等待，load panic 时它们同样会 panic
+func (m *manyLoad) run(load func(set func(string, Result))) {
+	defer m.cancel()
+	set := func(key string, r Result) {
+		select {
+		case m.results[key] <- r:
+		default:
+		}
+	}
+	defer func() {
+		m.panic = recover()
+		for key := range m.results {
+			set(key, Result{Err: errBatchAborted})
+		}
+	}()
+	load(set)
+}
+
+// workers 并发执行多个函数，Wait 在所有函数结束之后返回
+// 函数 panic 时在 Wait 的调用方中以相同的值 panic，而不是让进程崩溃
+type workers struct {
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	panic interface{} // panic 是第一个 panic 的值
+}
+
+// Go 在新的 goroutine 中执行 fn
+func (w *workers) Go(fn func()) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				w.mu.Lock()
+				if w.panic == nil {
+					w.panic = r
+				}
+				w.mu.Unlock()
+			}
+		}()
+		fn()
+	}()
+}
+
+// Wait 等待所有函数结束，有函数 panic 时以它的值 panic
+func (w *workers) Wait() {
+	w.wg.Wait()
+	if w.panic != nil {
+		panic(w.panic)
+	}
+}
+
+// errBatchAborted 表示批量加载没有给出 key 的结果就结束了，例如 getter panic
+var errBatchAborted = errors.New("pcache: batch load aborted")
+
+// loadOwned 加载 keys，远程节点的 key 按节点批量获取，失败的 key 依次尝试 pickPeers 返回的下一个节点，
+// 所有节点都失败或者由当前节点负责的 key 从数据源获取
+func (g *Group) loadOwned(ctx context.Context, keys []string, set func(string, Result)) {
+	var local []string
+	next := make(map[string][]Fetcher)
+	for _, key := range keys {
+		if fetchers := g.pickPeers(ctx, key); len(fetchers) > 0 {
+			next[key] = fetchers
+		} else {
+			local = append(local, key)
+		}
+	}
+	for len(next) > 0 {
+		byPeer := make(map[Fetcher][]string)
+		for key, fetchers := range next {
+			byPeer[fetchers[0]] = append(byPeer[fetchers[0]], key)
+		}
+		var (
+			mu    sync.Mutex
+			wg    workers
+			retry []string
+		)
+		for fetcher, peerKeys := range byPeer {
+			fetcher, peerKeys := fetcher, peerKeys
+			wg.Go(func() {
+				if failed := g.fetchMany(ctx, fetcher, peerKeys, set); len(failed) > 0 {
+					mu.Lock()
+					retry = append(retry, failed...)
+					mu.Unlock()
+				}
+			})
+		}
+		wg.Wait()
+		remaining := make(map[string][]Fetcher, len(retry))
+		for _, key := range retry {
+			if fetchers := next[key][1:]; len(fetchers) > 0 {
+				remaining[key] = fetchers
+			} else {
+				local = append(local, key)
+			}
+		}
+		next = remaining
+	}
+	if len(local) > 0 {
+		g.getManyLocally(ctx, local, set)
+	}
+}
+
+// fetchMany 从 fetcher 获取 keys，返回需要回源的 key
+// fetcher 实现了 MultiFetcher 时只发送一次请求，否则逐个并发获取
+func (g *Group) fetchMany(ctx context.Context, fetcher Fetcher, keys []string, set func(string, Result)) (retry []string) {
+	mf, ok := fetcher.(MultiFetcher)
+	if !ok {
+		var mu sync.Mutex
+		var wg workers
+		for _, key := range keys {
+			key := key
+			wg.Go(func() {
+				ver := g.versions.get(key)
+				value, err := g.fetch(ctx, fetcher, key)
+				if r, done := g.peerResult(ctx, key, ver, value, err); done {
+					set(key, r)
+					return
+				}
+				mu.Lock()
+				retry = append(retry, key)
+				mu.Unlock()
+			})
+		}
+		wg.Wait()
+		return retry
+	}
+
+	vers := make([]uint64, len(keys))
+	for i, key := range keys {
+		vers[i] = g.versions.get(key)
+	}
+	fctx, cancel := context.WithTimeout(ctx, g.peerTimeout)
+	values, errs, err := mf.FetchMulti(fctx, g.name, keys)
+	cancel()
+	if err != nil {
+		g.stats.peerErrors.Add(int64(len(keys)))
+		if ctx.Err() != nil {
+			for _, key := range keys {
+				set(key, Result{Err: ctx.Err()})
+			}
+			return nil
+		}
+		log.Printf("failed to get %d keys from peer, %s\n", len(keys), err.Error())
+		return keys
+	}
+	for i, key := range keys {
+		if r, done := g.peerResult(ctx, key, vers[i], values[i], errs[i]); done {
+			set(key, r)
+		} else {
+			retry = append(retry, key)
+		}
+	}
+	return retry
+}
+
+// getManyLocally 从数据源获取 keys
+// getter 实现了 BatchGetter 且不合并并发请求时一次获取所有的 key；
+// 否则逐个并发获取，合并并发请求时这些 key 会被 batcher 合并为一次调用
+func (g *Group) getManyLocally(ctx context.Context, keys []string, set func(string, Result)) {
+	bg, ok := g.getter.(BatchGetter)
+	if !ok || g.batcher != nil {
+		var wg workers
+		for _, key := range keys {
+			key := key
+			wg.Go(func() {
+				view, err := g.getLocally(ctx, key, g.versions.get(key))
+				set(key, Result{Value: view, Err: err})
+			})
+		}
+		wg.Wait()
+		return
+	}
+
+	vers := make([]uint64, len(keys))
+	for i, key := range keys {
+		vers[i] = g.versions.get(key)
+	}
+	values, err := bg.GetBatch(ctx, keys)
+	for i, key := range keys {
+		var r Result
+		bytes, ok := values[key]
+		switch {
+		case err != nil:
+			r.Value, r.Err = g.storeLoaded(key, vers[i], nil, time.Time{}, err)
+		case !ok:
+			r.Value, r.Err = g.storeLoaded(key, vers[i], nil, time.Time{}, ErrNotFound)
+		default:
+			r.Value, r.Err = g.storeLoaded(key, vers[i], bytes, time.Time{}, nil)
+		}
+		set(key, r)
+	}
+}
@@ -0,0 +1,340 @@
+package pcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// multiPeer 在 fakePeer 的基础上实现了 MultiFetcher，并记录批量请求的次数
+type multiPeer struct {
+	fakePeer
+	calls atomic.Int64
+}
+
+func (p *multiPeer) FetchMulti(ctx context.Context, group string, keys []string) ([]ByteView, []error, error) {
+	p.calls.Add(1)
+	values := make([]ByteView, len(keys))
+	errs := make([]error, len(keys))
+	for i, r := range p.group.GetMany(ctx, keys) {
+		values[i], errs[i] = r.Value, r.Err
+	}
+	return values, errs, nil
+}
+
+func TestGetMany(t *testing.T) {
+	var batches atomic.Int64
+	source := BatchGetterFunc(func(ctx context.Context, keys []string) (map[string][]byte, error) {
+		batches.Add(1)
+		values := make(map[string][]byte)
+		for _, key := range keys {
+			if !strings.HasSuffix(key, "missing") {
+				values[key] = []byte("value of " + key)
+			}
+		}
+		return values, nil
+	})
+	owner := &multiPeer{fakePeer: fakePeer{NewGroup("getmany-test-owner", 0, source)}}
+	g := NewGroup("getmany-test", 0, source)
+	g.RegisterPicker(multiPicker{owner})
+
+	keys := []string{"a", "remote1", "", "b", "remote2", "a", "missing", "remote-missing"}
+	results := g.GetMany(context.Background(), keys)
+	for i, key := range keys {
+		r := results[i]
+		switch {
+		case key == "":
+			if !errors.Is(r.Err, ErrKeyRequired) {
+				t.Fatalf("empty key: want ErrKeyRequired, but got %v", r.Err)
+			}
+		case strings.HasSuffix(key, "missing"):
+			if !errors.Is(r.Err, ErrNotFound) {
+				t.Fatalf("%s: want ErrNotFound, but got %v", key, r.Err)
+			}
+		default:
+			if r.Err != nil || r.Value.String() != "value of "+key {
+				t.Fatalf("%s: want value of %s, but got %q, %v", key, key, r.Value.String(), r.Err)
+			}
+		}
+	}
+	if calls := owner.calls.Load(); calls != 1 {
+		t.Fatalf("remote keys should be fetched in 1 request, but got %d", calls)
+	}
+	// 当前节点和远程节点各调用一次数据源
+	if n := batches.Load(); n != 2 {
+		t.Fatalf("local keys should be loaded in 1 batch on each node, but got %d", n)
+	}
+	if stats := g.Stats(); stats.Gets != int64(len(keys)) || stats.PeerLoads != 2 || stats.LocalLoads != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// 再次获取时全部命中缓存
+	g.GetMany(context.Background(), []string{"a", "b", "remote1"})
+	if n := batches.Load(); n != 2 {
+		t.Fatalf("cached keys should not be loaded again, but got %d batches", n)
+	}
+}
+
+// multiPicker 将 remote 开头的 key 交给 owner 处理
+type multiPicker struct {
+	owner *multiPeer
+}
+
+func (p multiPicker) Pick(key string) (Fetcher, bool) {
+	if strings.HasPrefix(key, "remote") {
+		return p.owner, true
+	}
+	return nil, false
+}
+
+func TestGetManyPeerFallback(t *testing.T) {
+	source := GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	g := NewGroup("getmany-fallback-test", 0, source)
+	g.RegisterPicker(&replicaPicker{replicas: []Fetcher{downPeer{}}})
+	results := g.GetMany(context.Background(), []string{"Tom", "Jack"})
+	for _, r := range results {
+		if r.Err != nil || r.Value.Len() == 0 {
+			t.Fatalf("keys should be loaded locally when peer is down, but got %v", r.Err)
+		}
+	}
+	if stats := g.Stats(); stats.PeerErrors != 2 || stats.LocalLoads != 2 {
+		t.Fatalf("want 2 peer errors and 2 local loads, but got %+v", stats)
+	}
+}
+
+func TestGetManyReplicas(t *testing.T) {
+	source := GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	replica := &fakePeer{NewGroup("getmany-replica-test-replica", 0, source)}
+	g, err := NewGroupWithOptions("getmany-replica-test", source, GroupOptions{Replication: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.RegisterPicker(&replicaPicker{replicas: []Fetcher{downPeer{}, replica}})
+	for _, r := range g.GetMany(context.Background(), []string{"Tom", "Jack"}) {
+		if r.Err != nil || r.Value.Len() == 0 {
+			t.Fatalf("keys should be loaded from the replica, but got %v", r.Err)
+		}
+	}
+	if stats := g.Stats(); stats.PeerErrors != 2 || stats.PeerLoads != 2 || stats.LocalLoads != 0 {
+		t.Fatalf("want 2 peer errors and 2 peer loads, but got %+v", stats)
+	}
+}
+
+// TestGetManyDedup 检查并发的 Get 和 GetMany 共享同一次加载
+func TestGetManyDedup(t *testing.T) {
+	var loads atomic.Int64
+	start, release := make(chan struct{}), make(chan struct{})
+	g := NewGroup("getmany-dedup-test", 0, GetterFunc(func(key string) ([]byte, error) {
+		if loads.Add(1) == 1 {
+			close(start)
+			<-release
+		}
+		return []byte(key), nil
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Get("Tom")
+	}()
+	<-start
+	results := make(chan []Result)
+	go func() {
+		results <- g.GetMany(context.Background(), []string{"Tom", "Jack"})
+	}()
+	// Jack 不需要等待 Tom 的加载
+	for loads.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	for _, r := range <-results {
+		if r.Err != nil || r.Value.Len() == 0 {
+			t.Fatalf("want value, but got %v", r.Err)
+		}
+	}
+	<-done
+	if n := loads.Load(); n != 2 {
+		t.Fatalf("Tom should be loaded once, but loaded %d keys", n)
+	}
+	if n := g.Stats().Dedups; n != 1 {
+		t.Fatalf("want 1 dedup, but got %d", n)
+	}
+}
+
+// TestGetManyPanic 检查 GetMany 中 getter 的 panic 传递给调用方，而不是让进程崩溃
+func TestGetManyPanic(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		panic("boom")
+	})
+	owner := NewGroup("getmany-panic-test-owner", 0, getter)
+	g := NewGroup("getmany-panic-test", 0, getter)
+	g.RegisterPicker(&replicaPicker{replicas: []Fetcher{&fakePeer{owner}}})
+	// 分别从远程节点和数据源获取
+	for i, ctx := range []context.Context{context.Background(), withPeerRequest(context.Background())} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "boom") {
+					t.Fatalf("GetMany should panic with boom, but got %v", r)
+				}
+			}()
+			g.GetMany(ctx, []string{"a" + strconv.Itoa(i), "b" + strconv.Itoa(i)})
+		}()
+	}
+}
+
+func TestBatchCoalesce(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]string
+	)
+	called, release := make(chan struct{}), make(chan struct{})
+	source := BatchGetterFunc(func(ctx context.Context, keys []string) (map[string][]byte, error) {
+		mu.Lock()
+		batches = append(batches, keys)
+		mu.Unlock()
+		close(called)
+		<-release
+		values := make(map[string][]byte)
+		for _, key := range keys {
+			values[key] = []byte("value of " + key)
+		}
+		return values, nil
+	})
+	// 等待时间足够长，10 个不同的 key 都到达之后批次才会执行
+	g, err := NewGroupWithOptions("batch-coalesce-test", source, GroupOptions{BatchWindow: time.Hour, BatchMaxKeys: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGroupWithOptions("batch-invalid-window-test", source, GroupOptions{BatchWindow: -1}); err == nil {
+		t.Fatal("negative BatchWindow should be rejected")
+	}
+
+	var wg sync.WaitGroup
+	get := func(key string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := g.Get(key); err != nil || v.String() != "value of "+key {
+				t.Errorf("%s: want value of %s, but got %q, %v", key, key, v.String(), err)
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		get(strconv.Itoa(i))
+	}
+	// 批次正在执行时再次请求这 10 个 key，它们会等待已有的加载或者命中缓存，不会产生新的批次
+	<-called
+	for i := 0; i < 10; i++ {
+		get(strconv.Itoa(i))
+	}
+	close(release)
+	wg.Wait()
+	if len(batches) != 1 || len(batches[0]) != 10 {
+		t.Fatalf("concurrent misses should be coalesced into 1 batch of 10 keys, but got %v", batches)
+	}
+	if stats := g.Stats(); stats.Batches != 1 || stats.LocalLoads != 10 {
+		t.Fatalf("want 1 batch and 10 local loads, but got %+v", stats)
+	}
+}
+
+// TestBatchDisabled 检查没有设置 BatchWindow 时不合并并发请求
+func TestBatchDisabled(t *testing.T) {
+	var batches atomic.Int64
+	source := BatchGetterFunc(func(ctx context.Context, keys []string) (map[string][]byte, error) {
+		batches.Add(1)
+		return map[string][]byte{keys[0]: []byte(keys[0])}, nil
+	})
+	g := NewGroup("batch-disabled-test", 0, source)
+	if g.batcher != nil {
+		t.Fatal("batching should be disabled by default")
+	}
+	if v, err := g.Get("Tom"); err != nil || v.String() != "Tom" {
+		t.Fatalf("want Tom, but got %q, %v", v.String(), err)
+	}
+	if n := g.Stats().Batches; n != 0 {
+		t.Fatalf("want no batches, but got %d", n)
+	}
+}
+
+func TestBatchMaxKeys(t *testing.T) {
+	source := BatchGetterFunc(func(ctx context.Context, keys []string) (map[string][]byte, error) {
+		values := make(map[string][]byte)
+		for _, key := range keys {
+			values[key] = []byte(key)
+		}
+		return values, nil
+	})
+	// 等待时间足够长，只有达到 BatchMaxKeys 时才会执行
+	g, err := NewGroupWithOptions("batch-max-keys-test", source, GroupOptions{BatchWindow: time.Hour, BatchMaxKeys: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			if _, err := g.Get(key); err != nil {
+				t.Errorf("%s: %v", key, err)
+			}
+		}(key)
+	}
+	wg.Wait()
+	if n := g.Stats().Batches; n != 1 {
+		t.Fatalf("full batch should be flushed at once, but got %d batches", n)
+	}
+}
+
+func TestBatchContext(t *testing.T) {
+	source := BatchGetterFunc(func(ctx context.Context, keys []string) (map[string][]byte, error) {
+		return nil, nil
+	})
+	g, err := NewGroupWithOptions("batch-context-test", source, GroupOptions{BatchWindow: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.GetContext(ctx, "Tom"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, but got %v", err)
+	}
+	if n := g.Stats().Batches; n != 0 {
+		t.Fatalf("abandoned batch should not be loaded, but got %d batches", n)
+	}
+	// 放弃的批次不影响之后的请求
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.GetContext(ctx, "Jack"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, but got %v", err)
+	}
+
+	if _, err := NewGroupWithOptions("batch-invalid-test", source, GroupOptions{BatchMaxKeys: -1}); err == nil {
+		t.Fatal("negative BatchMaxKeys should be rejected")
+	}
+}
+
+// TestBatchContextValue 检查批次的 ctx 保留调用方 ctx 中的值
+func TestBatchContextValue(t *testing.T) {
+	type ctxKey struct{}
+	source := BatchGetterFunc(func(ctx context.Context, keys []string) (map[string][]byte, error) {
+		user, _ := ctx.Value(ctxKey{}).(string)
+		return map[string][]byte{keys[0]: []byte(user)}, nil
+	})
+	g, err := NewGroupWithOptions("batch-context-value-test", source, GroupOptions{BatchWindow: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "Tom")
+	if v, err := g.GetContext(ctx, "key"); err != nil || v.String() != "Tom" {
+		t.Fatalf("batch ctx should carry caller values, but got %q, %v", v.String(), err)
+	}
+}
@@ -0,0 +1,120 @@
+package pcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"pcache/singleflight"
+)
+
+// defaultBatchMaxKeys 是一次 BatchGetter 调用的默认最大 key 数量
+const defaultBatchMaxKeys = 100
+
+// batcher 将一段时间内并发的多个 key 合并为一次 BatchGetter 调用
+// 同一个 key 的并发请求已经被 Group 的 flight 合并，因此一个批次中的 key 不会重复
+type batcher struct {
+	getter  BatchGetter
+	window  time.Duration // window 是第一个 key 到达后等待其他 key 的时间
+	maxKeys int           // maxKeys 是一个批次的最大 key 数量，达到后立即执行
+	stats   *groupStats
+
+	mu      sync.Mutex
+	pending *batch // pending 是正在收集 key 的批次
+}
+
+// batch 是一次 BatchGetter 调用
+type batch struct {
+	keys    []string
+	started bool // started 表示批次已经开始执行，不再接收新的 key
+	waiting int  // waiting 是仍在等待结果的调用方数量，为 0 时取消 ctx
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	done   chan struct{} // done 在 BatchGetter 返回后关闭
+	values map[string][]byte
+	err    error
+}
+
+func newBatcher(getter BatchGetter, window time.Duration, maxKeys int, stats *groupStats) *batcher {
+	return &batcher{getter: getter, window: window, maxKeys: maxKeys, stats: stats}
+}
+
+// get 将 key 加入正在收集的批次，并等待批次的结果
+// ctx 结束时不再等待，所有调用方都放弃后批次的 ctx 会被取消
+// 批次的 ctx 保留第一个 key 的 ctx 中的值，但不随它结束
+func (b *batcher) get(ctx context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	p := b.pending
+	if p == nil {
+		p = &batch{done: make(chan struct{})}
+		p.ctx, p.cancel = context.WithCancel(singleflight.WithoutCancel(ctx))
+		b.pending = p
+		time.AfterFunc(b.window, func() { b.flush(p) })
+	}
+	p.keys = append(p.keys, key)
+	p.waiting++
+	full := len(p.keys) >= b.maxKeys
+	if full {
+		// 之后的 key 使用新的批次，不会在 flush 开始之前加入已满的批次
+		b.pending = nil
+	}
+	b.mu.Unlock()
+	if full {
+		go b.flush(p)
+	}
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		b.mu.Lock()
+		p.waiting--
+		if p.waiting == 0 {
+			// 之后的 key 使用新的批次，而不是加入已经取消的批次
+			p.cancel()
+			if b.pending == p {
+				b.pending = nil
+			}
+		}
+		b.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	value, ok := p.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// flush 执行批次，同一个批次只会执行一次
+func (b *batcher) flush(p *batch) {
+	b.mu.Lock()
+	if p.started {
+		b.mu.Unlock()
+		return
+	}
+	p.started = true
+	if b.pending == p {
+		b.pending = nil
+	}
+	b.mu.Unlock()
+
+	defer close(p.done)
+	defer p.cancel()
+	// 所有调用方都已经放弃
+	if err := p.ctx.Err(); err != nil {
+		p.err = err
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			p.err = fmt.Errorf("pcache: batch getter panic: %v", r)
+		}
+	}()
+	b.stats.batches.Add(1)
+	p.values, p.err = b.getter.GetBatch(p.ctx, p.keys)
+}
@@ -1,10 +1,30 @@
 package pcache
 
+import (
+	"sync/atomic"
+	"time"
+)
+
 type ByteView struct {
 	// 如果 b 不为空，则由 b 存储数据
 	b []byte
 	// 如果 b 为空，则由 s 存储数据
 	s string
+	// e 是数据的过期时间，零值表示永不过期
+	e time.Time
+	// d 保存 TypedGroup 解码后的对象，同一个缓存条目的所有副本共享，nil 表示不保存
+	d *decoded
+}
+
+// NewByteView 返回保存 b 的副本的 ByteView，expire 为零值表示永不过期
+// 在当前包之外实现 Fetcher 时，使用它构造 Fetch 返回的值
+func NewByteView(b []byte, expire time.Time) ByteView {
+	return ByteView{b: cloneBytes(b), e: expire}
+}
+
+// Expire 返回数据的过期时间，零值表示永不过期
+func (v ByteView) Expire() time.Time {
+	return v.e
 }
 
 func (v ByteView) Len() int {
@@ -33,3 +53,24 @@ func cloneBytes(b []byte) []byte {
 	copy(c, b)
 	return c
 }
+
+// decoded 保存字节解码后的对象，避免每次命中缓存都重新解码
+type decoded struct {
+	v atomic.Value // v 中保存的是 decodedValue，保证每次存入的类型一致
+}
+
+type decodedValue struct {
+	obj interface{}
+}
+
+// load 返回已经解码的对象，没有时返回 nil
+func (d *decoded) load() interface{} {
+	if v, ok := d.v.Load().(decodedValue); ok {
+		return v.obj
+	}
+	return nil
+}
+
+func (d *decoded) store(obj interface{}) {
+	d.v.Store(decodedValue{obj: obj})
+}
@@ -3,38 +3,219 @@ package pcache
 import (
 	"pcache/purgekit"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// purgeInterval 是后台清理过期条目的间隔
+const purgeInterval = time.Minute
+
+var (
+	// totalBytes 是进程内所有缓存占用的字节数
+	totalBytes atomic.Int64
+	// maxTotalBytes 是进程内所有缓存可占用的最大字节数，0 表示不限制
+	maxTotalBytes atomic.Int64
+)
+
+// SetMaxBytes 设置进程内所有 Group 的缓存可占用的总字节数，0 表示不限制
+// 超出限制时，正在写入的缓存会按照自己的淘汰策略淘汰条目，刚写入的条目不会被淘汰
+func SetMaxBytes(maxBytes int64) {
+	maxTotalBytes.Store(maxBytes)
+}
+
+// CacheStats 是缓存的统计信息
+type CacheStats struct {
+	Bytes     int64 // Bytes 是缓存占用的字节数
+	Items     int64 // Items 是缓存的条目数
+	Gets      int64 // Gets 是查询缓存的次数
+	Hits      int64 // Hits 是缓存命中的次数
+	Evictions int64 // Evictions 是因为容量限制被淘汰的条目数
+}
+
 type cache struct {
 	m          sync.RWMutex
-	lru        purgekit.Cache
+	lru        purgekit.TypedCache[string, ByteView]
+	policy     string
 	maxEntries int
+	maxBytes   int64
+	stop       chan struct{} // stop 关闭时后台清理协程退出，nil 表示尚未启动
+	closed     bool
+	adding     bool // adding 表示正在写入，此时移除的条目都是因为容量被淘汰
+
+	nget   atomic.Int64
+	nhit   atomic.Int64
+	nevict atomic.Int64
 }
 
-func newCache(maxEntries int) *cache {
-	return &cache{
+// newCache 创建一个使用 policy 淘汰策略的缓存
+func newCache(policy string, maxEntries int, maxBytes int64) (*cache, error) {
+	c := &cache{
+		policy:     policy,
 		maxEntries: maxEntries,
-		lru:        purgekit.NewCache("lru", maxEntries, nil),
+		maxBytes:   maxBytes,
+	}
+	lru, err := purgekit.NewTyped(policy, purgekit.Config[string, ByteView]{
+		MaxEntries: maxEntries,
+		MaxBytes:   maxBytes,
+		SizeFunc:   sizeOf,
+		OnEnvicted: c.onEvicted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.lru = lru
+	return c, nil
+}
+
+// onEvicted 统计因为容量限制被淘汰的条目，调用时已经持有锁
+func (c *cache) onEvicted(key string, value ByteView) {
+	if c.adding {
+		c.nevict.Add(1)
 	}
 }
 
+// sizeOf 计算一个缓存条目占用的字节数，包括 key 的长度，不包括 TypedGroup 解码后的对象
+func sizeOf(key string, value ByteView) int64 {
+	return int64(len(key) + value.Len())
+}
+
 func (c *cache) add(key string, value ByteView) {
-	c.m.RLock()
-	defer c.m.RUnlock()
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.closed {
+		return
+	}
 	if c.lru == nil {
 		panic("please init cache first")
 	}
-	c.lru.Add(key, value)
+	before := c.lru.Bytes()
+	c.adding = true
+	c.lru.AddWithExpire(key, value, value.e)
+	// 超出进程级别的限制时，从当前缓存继续淘汰，刚写入的条目不会被淘汰
+	// totalBytes 通过 CAS 更新，保证并发写入的缓存不会同时基于旧的总量判断没有超出限制
+	held := false
+	for {
+		delta := c.lru.Bytes() - before
+		if held {
+			delta += sizeOf(key, value)
+		}
+		total := totalBytes.Load()
+		if max := maxTotalBytes.Load(); max > 0 && total+delta > max && c.lru.Len() > 0 {
+			if k, _, ok := c.lru.Evict(); ok {
+				if k == key {
+					// 淘汰策略选中了刚写入的条目，暂时移出，淘汰结束后重新写入
+					held = true
+					c.nevict.Add(-1)
+				}
+				continue
+			}
+		}
+		if totalBytes.CompareAndSwap(total, total+delta) {
+			break
+		}
+	}
+	if held {
+		after := c.lru.Bytes() + sizeOf(key, value)
+		c.lru.AddWithExpire(key, value, value.e)
+		// 重新写入时缓存自身的容量限制可能淘汰其他条目，修正预留的字节数
+		totalBytes.Add(c.lru.Bytes() - after)
+	}
+	c.adding = false
+	if !value.e.IsZero() && c.stop == nil {
+		c.stop = make(chan struct{})
+		go c.purge(c.stop)
+	}
 }
 
+// get 会修改淘汰策略的内部状态，因此需要写锁
 func (c *cache) get(key string) (value ByteView, ok bool) {
-	c.m.RLock()
-	defer c.m.RUnlock()
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.nget.Add(1)
 	if c.lru == nil {
 		return
 	}
+	before := c.lru.Bytes()
+	defer func() { totalBytes.Add(c.lru.Bytes() - before) }()
 	if v, ok := c.lru.Get(key); ok {
-		return v.(ByteView), ok
+		c.nhit.Add(1)
+		return v, ok
 	}
 	return
 }
+
+// remove 移除 key 对应的缓存
+func (c *cache) remove(key string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.lru == nil {
+		return
+	}
+	before := c.lru.Bytes()
+	c.lru.Remove(key)
+	totalBytes.Add(c.lru.Bytes() - before)
+}
+
+// bytes 返回缓存占用的字节数
+func (c *cache) bytes() int64 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	if c.lru == nil {
+		return 0
+	}
+	return c.lru.Bytes()
+}
+
+// stats 返回缓存的统计信息
+func (c *cache) stats() CacheStats {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	stats := CacheStats{
+		Gets:      c.nget.Load(),
+		Hits:      c.nhit.Load(),
+		Evictions: c.nevict.Load(),
+	}
+	if c.lru != nil {
+		stats.Bytes = c.lru.Bytes()
+		stats.Items = int64(c.lru.Len())
+	}
+	return stats
+}
+
+// purge 定期清理过期的条目，直到 stop 被关闭
+func (c *cache) purge(stop chan struct{}) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.m.Lock()
+			if c.lru != nil {
+				before := c.lru.Bytes()
+				c.lru.RemoveExpired()
+				totalBytes.Add(c.lru.Bytes() - before)
+			}
+			c.m.Unlock()
+		}
+	}
+}
+
+// close 停止后台清理协程，并释放缓存占用的字节数
+func (c *cache) close() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	if c.lru != nil {
+		totalBytes.Add(-c.lru.Bytes())
+		c.lru = nil
+	}
+}
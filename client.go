@@ -4,34 +4,180 @@ import (
 	"context"
 	"fmt"
 	pb "pcache/pcachepb"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
+const (
+	// defaultFetchTimeout 是 ctx 没有设置超时时 Fetch 使用的超时时间
+	defaultFetchTimeout = 10 * time.Second
+	// peerMetadataKey 是标记请求由其他节点转发的 gRPC metadata
+	// 收到带有该标记的请求的节点总是在本地处理，不会再次转发
+	peerMetadataKey = "pcache-peer"
+)
+
+// defaultDialOptions 是连接 remote peer 时默认使用的选项
+// 连接断开后 grpc 会按照指数退避自动重连
+var defaultDialOptions = []grpc.DialOption{
+	grpc.WithTransportCredentials(insecure.NewCredentials()),
+	grpc.WithConnectParams(grpc.ConnectParams{
+		Backoff:           backoff.DefaultConfig,
+		MinConnectTimeout: 5 * time.Second,
+	}),
+}
+
+// client 持有到一个 remote peer 的长连接，连接在第一次请求时建立
 type client struct {
-	name string // 服务名称：pcache/ip:addr
+	addr     string            // addr 是 remote peer 的地址 ip:port
+	dialOpts []grpc.DialOption // dialOpts 是建立连接时使用的选项
+	inflight atomic.Int64      // inflight 是正在进行的请求数，用于有界负载
+
+	mu     sync.Mutex
+	conn   *grpc.ClientConn
+	closed bool
 }
 
 // Fetch 从 remote peer 获取对应的缓存值
-func (c *client) Fetch(group string, key string) ([]byte, error) {
-	conn, err := grpc.Dial(c.name)
+// 如果 ctx 没有设置超时，使用 defaultFetchTimeout
+func (c *client) Fetch(ctx context.Context, group string, key string) (ByteView, error) {
+	defer clientFetchLatency.since(time.Now())
+	var resp *pb.Response
+	err := c.call(ctx, func(ctx context.Context, grpcClient pb.PcacheClient) (err error) {
+		resp, err = grpcClient.Get(ctx, &pb.Request{Group: group, Key: key})
+		return err
+	})
+	if err != nil {
+		return ByteView{}, fmt.Errorf("could not get %s/%s from peer %s: %w", group, key, c.addr, err)
+	}
+	value := ByteView{b: resp.GetValue()}
+	if resp.GetExpire() != 0 {
+		value.e = time.Unix(0, resp.GetExpire())
+	}
+	return value, nil
+}
+
+// FetchMulti 从 remote peer 一次获取多个 key 的值，结果与 keys 一一对应
+func (c *client) FetchMulti(ctx context.Context, group string, keys []string) ([]ByteView, []error, error) {
+	defer clientFetchLatency.since(time.Now())
+	var resp *pb.MultiResponse
+	err := c.call(ctx, func(ctx context.Context, grpcClient pb.PcacheClient) (err error) {
+		resp, err = grpcClient.GetMulti(ctx, &pb.MultiRequest{Group: group, Keys: keys})
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get %d keys of %s from peer %s: %w", len(keys), group, c.addr, err)
+	}
+	items := resp.GetItems()
+	if len(items) != len(keys) {
+		return nil, nil, fmt.Errorf("peer %s returned %d items for %d keys", c.addr, len(items), len(keys))
+	}
+	values := make([]ByteView, len(keys))
+	errs := make([]error, len(keys))
+	for i, item := range items {
+		if item.GetCode() != 0 {
+			errs[i] = fromItem(item)
+			continue
+		}
+		values[i] = ByteView{b: item.GetValue()}
+		if item.GetExpire() != 0 {
+			values[i].e = time.Unix(0, item.GetExpire())
+		}
+	}
+	return values, errs, nil
+}
+
+// Set 将 key 的值写入 remote peer
+func (c *client) Set(ctx context.Context, group string, key string, value []byte, expire time.Time) error {
+	req := &pb.SetRequest{Group: group, Key: key, Value: value}
+	if !expire.IsZero() {
+		req.Expire = expire.UnixNano()
+	}
+	err := c.call(ctx, func(ctx context.Context, grpcClient pb.PcacheClient) error {
+		_, err := grpcClient.Set(ctx, req)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("could not set %s/%s to peer %s: %w", group, key, c.addr, err)
+	}
+	return nil
+}
+
+// Remove 删除 remote peer 中 key 的缓存
+func (c *client) Remove(ctx context.Context, group string, key string) error {
+	err := c.call(ctx, func(ctx context.Context, grpcClient pb.PcacheClient) error {
+		_, err := grpcClient.Remove(ctx, &pb.Request{Group: group, Key: key})
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("could not remove %s/%s from peer %s: %w", group, key, c.addr, err)
 	}
-	defer conn.Close()
-	grpcClient := pb.NewPcacheClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	resp, err := grpcClient.Get(ctx, &pb.Request{Group: group, Key: key})
+	return nil
+}
+
+// call 使用到 remote peer 的连接执行 fn
+// 如果 ctx 没有设置超时，使用 defaultFetchTimeout
+// 请求会带上 peerMetadataKey，返回的 gRPC 状态会被还原为对应的错误，例如 ErrNotFound
+func (c *client) call(ctx context.Context, fn func(context.Context, pb.PcacheClient) error) error {
+	c.inflight.Add(1)
+	defer c.inflight.Add(-1)
+	conn, err := c.getConn()
 	if err != nil {
-		return nil, fmt.Errorf("could not get %s/%s from peer %s", group, key, c.name)
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultFetchTimeout)
+		defer cancel()
 	}
-	return resp.GetValue(), nil
+	ctx = metadata.AppendToOutgoingContext(ctx, peerMetadataKey, "1")
+	return fromStatus(fn(ctx, pb.NewPcacheClient(conn)))
 }
 
-func NewClient(service string) *client {
-	return &client{name: service}
+// getConn 返回到 remote peer 的连接，第一次调用时建立连接
+func (c *client) getConn() (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, fmt.Errorf("%w: client for %s closed", ErrPeerUnavailable, c.addr)
+	}
+	if c.conn == nil {
+		conn, err := grpc.Dial(c.addr, c.dialOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPeerUnavailable, err)
+		}
+		c.conn = conn
+	}
+	return c.conn, nil
+}
+
+// Close 关闭到 remote peer 的连接，之后的请求都会返回 ErrPeerUnavailable
+func (c *client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	conn := c.conn
+	c.conn = nil
+	return conn.Close()
+}
+
+// NewClient 创建到 addr 的 client，opts 会追加在 defaultDialOptions 之后
+func NewClient(addr string, opts ...grpc.DialOption) *client {
+	dialOpts := make([]grpc.DialOption, 0, len(defaultDialOptions)+len(opts))
+	dialOpts = append(dialOpts, defaultDialOptions...)
+	dialOpts = append(dialOpts, opts...)
+	return &client{addr: addr, dialOpts: dialOpts}
 }
 
-var _ Fetcher = (*client)(nil)
+var (
+	_ Fetcher      = (*client)(nil)
+	_ MultiFetcher = (*client)(nil)
+)
@@ -0,0 +1,92 @@
+package pcache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	pb "pcache/pcachepb"
+
+	"google.golang.org/grpc"
+)
+
+// startTestServer 在随机端口上启动 server 的 gRPC 服务
+func startTestServer(t *testing.T) (*server, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := NewServer(lis.Addr().String())
+	grpcServer := grpc.NewServer()
+	pb.RegisterPcacheServer(grpcServer, s)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+	return s, lis.Addr().String()
+}
+
+func TestClientFetch(t *testing.T) {
+	NewGroup("client-test", 0, GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, ErrNotFound
+		}
+		return []byte(key), nil
+	}))
+	_, addr := startTestServer(t)
+	c := NewClient(addr)
+	defer c.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		view, err := c.Fetch(ctx, "client-test", "Tom")
+		if err != nil || view.String() != "Tom" {
+			t.Fatalf("want Tom, but got %q, %v", view.String(), err)
+		}
+	}
+	conn := c.conn
+	if conn == nil {
+		t.Fatal("client should keep the connection after Fetch")
+	}
+	if _, err := c.Fetch(ctx, "client-test", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, but got %v", err)
+	}
+	if _, err := c.Fetch(ctx, "no-such-group", "Tom"); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("want ErrGroupNotFound, but got %v", err)
+	}
+	if c.conn != conn {
+		t.Fatal("client should reuse the connection")
+	}
+
+	c.Close()
+	if _, err := c.Fetch(ctx, "client-test", "Tom"); !errors.Is(err, ErrPeerUnavailable) {
+		t.Fatalf("want ErrPeerUnavailable after Close, but got %v", err)
+	}
+}
+
+func TestClientFetchMulti(t *testing.T) {
+	NewGroup("client-multi-test", 0, GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, ErrNotFound
+		}
+		return []byte(key), nil
+	}))
+	_, addr := startTestServer(t)
+	c := NewClient(addr)
+	defer c.Close()
+
+	ctx := context.Background()
+	values, errs, err := c.FetchMulti(ctx, "client-multi-test", []string{"Tom", "missing", "", "Jack"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0].String() != "Tom" || errs[0] != nil || values[3].String() != "Jack" || errs[3] != nil {
+		t.Fatalf("want Tom and Jack, but got %v, %v", values, errs)
+	}
+	if !errors.Is(errs[1], ErrNotFound) || !errors.Is(errs[2], ErrKeyRequired) {
+		t.Fatalf("want ErrNotFound and ErrKeyRequired, but got %v", errs)
+	}
+	if _, _, err := c.FetchMulti(ctx, "no-such-group", []string{"Tom"}); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("want ErrGroupNotFound, but got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package pcache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 负责对象与字节之间的转换，TypedGroup 使用它读写缓存
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// JSONCodec 使用 encoding/json 编码对象
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// GobCodec 使用 encoding/gob 编码对象
+// 每个值单独编码，都会带上类型信息，适合结构复杂、不方便使用 JSON 的类型
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Encode(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// ProtoCodec 使用 protobuf 编码对象，T 是生成的消息的指针类型，例如 *pb.Item
+type ProtoCodec[T proto.Message] struct{}
+
+func (ProtoCodec[T]) Encode(v T) ([]byte, error) {
+	return proto.Marshal(v)
+}
+
+func (ProtoCodec[T]) Decode(data []byte) (T, error) {
+	// 生成的消息在 nil 指针上也可以调用 ProtoReflect，用它创建新的消息
+	var zero T
+	v := zero.ProtoReflect().Type().New().Interface().(T)
+	if err := proto.Unmarshal(data, v); err != nil {
+		return zero, err
+	}
+	return v, nil
+}
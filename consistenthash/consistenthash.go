@@ -1,53 +1,287 @@
 package consistenthash
 
 import (
+	"fmt"
 	"hash/crc32"
+	"math"
 	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
 )
 
 // HashFunc 定义哈希函数的输入输出
 type HashFunc func(data []byte) uint32
 
+// Map 是一致性哈希环
+// 每次修改都会生成一个新的只读哈希环并原子地替换旧的，GetPeer 不需要加锁
 type Map struct {
 	hash     HashFunc
-	replicas int            // 虚拟节点倍数
-	ring     []int          // uint32 哈希环
-	hashMap  map[int]string // hashvalue 到 节点之间的映射
+	replicas int        // 虚拟节点倍数
+	mu       sync.Mutex // mu 保证修改操作串行执行
+	r        atomic.Pointer[ring]
+	bound    atomic.Pointer[boundedLoad] // bound 为 nil 时不限制节点的负载
+}
+
+// LoadFunc 返回节点当前的负载，例如正在处理的请求数
+type LoadFunc func(peer string) int64
+
+// boundedLoad 是有界负载模式的配置
+type boundedLoad struct {
+	epsilon float64
+	load    LoadFunc
+}
+
+// ring 是某一时刻哈希环的快照，创建后不再修改
+type ring struct {
+	ring    []int          // uint32 哈希环
+	hashMap map[int]string // hashvalue 到 节点之间的映射
+	peers   map[string]int // 环上的所有节点及其权重
+	weight  int            // weight 是所有节点的权重之和
 }
 
 func New(replicas int, fn HashFunc) *Map {
 	m := &Map{
 		replicas: replicas,
 		hash:     fn,
-		hashMap:  make(map[int]string),
 	}
 	if m.hash == nil {
 		m.hash = crc32.ChecksumIEEE
 	}
+	m.r.Store(&ring{
+		hashMap: make(map[int]string),
+		peers:   make(map[string]int),
+	})
 	return m
 }
 
-// Registe 将节点注册到哈希环上
+// load 返回当前哈希环的快照
+func (m *Map) load() *ring {
+	return m.r.Load()
+}
+
+// clone 复制当前的哈希环，调用时需要持有锁
+func (m *Map) clone() *ring {
+	old := m.load()
+	r := &ring{
+		ring:    make([]int, len(old.ring), len(old.ring)+m.replicas),
+		hashMap: make(map[int]string, len(old.hashMap)),
+		peers:   make(map[string]int, len(old.peers)),
+		weight:  old.weight,
+	}
+	copy(r.ring, old.ring)
+	for k, v := range old.hashMap {
+		r.hashMap[k] = v
+	}
+	for k, v := range old.peers {
+		r.peers[k] = v
+	}
+	return r
+}
+
+// Registe 将节点以权重 1 注册到哈希环上
 func (m *Map) Registe(peers ...string) {
+	m.RegisteWithWeight(1, peers...)
+}
+
+// RegisteWithWeight 将节点注册到哈希环上，每个节点有 replicas*weight 个虚拟节点
+// weight 小于 1 时按 1 处理，已经存在的节点会更新为新的权重
+func (m *Map) RegisteWithWeight(weight int, peers ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.registe(peers, uniformWeights(weight, peers))
+}
+
+// RegisteWeights 以各自的权重将多个节点注册到哈希环上，只生成一次新的哈希环
+// 新的节点按名称顺序加入，已经存在的节点会更新为新的权重
+func (m *Map) RegisteWeights(weights map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.registe(sortedPeers(weights), weights)
+}
+
+// registe 按 peers 的顺序将节点加入哈希环，调用时需要持有锁
+func (m *Map) registe(peers []string, weights map[string]int) {
+	weightOf := func(peerName string) int {
+		if w := weights[peerName]; w > 1 {
+			return w
+		}
+		return 1
+	}
+	r := m.clone()
+	var changed []string
+	for _, peerName := range peers {
+		if w, ok := r.peers[peerName]; ok && w != weightOf(peerName) {
+			changed = append(changed, peerName)
+		}
+	}
+	// 权重变化的节点先移除再以新的权重加入
+	m.remove(r, changed)
+	for _, peerName := range peers {
+		if _, ok := r.peers[peerName]; ok {
+			continue
+		}
+		weight := weightOf(peerName)
+		r.peers[peerName] = weight
+		r.weight += weight
+		for i := 0; i < m.replicas*weight; i++ {
+			hashValue := int(m.hash([]byte(strconv.Itoa(i) + peerName)))
+			// 哈希冲突时保留先注册的节点
+			if _, ok := r.hashMap[hashValue]; ok {
+				continue
+			}
+			r.ring = append(r.ring, hashValue)
+			r.hashMap[hashValue] = peerName
+		}
+	}
+	sort.Ints(r.ring)
+	m.r.Store(r)
+}
+
+// Remove 将节点从哈希环上移除，其余节点的虚拟节点保持不变
+func (m *Map) Remove(peers ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.clone()
+	if m.remove(r, peers) {
+		m.r.Store(r)
+	}
+}
+
+// remove 从 r 中移除节点及其虚拟节点，返回是否有节点被移除，调用时需要持有锁
+func (m *Map) remove(r *ring, peers []string) bool {
+	removed := false
 	for _, peerName := range peers {
-		for i := 0; i < m.replicas; i++ {
+		if weight, ok := r.peers[peerName]; ok {
+			delete(r.peers, peerName)
+			r.weight -= weight
+			removed = true
+		}
+	}
+	if !removed {
+		return false
+	}
+	hashes := r.ring[:0]
+	freed := make(map[int]struct{})
+	for _, hashValue := range r.ring {
+		if _, ok := r.peers[r.hashMap[hashValue]]; ok {
+			hashes = append(hashes, hashValue)
+		} else {
+			delete(r.hashMap, hashValue)
+			freed[hashValue] = struct{}{}
+		}
+	}
+	r.ring = hashes
+	if len(freed) == 0 {
+		return true
+	}
+	// 哈希冲突时被移除节点占用的位置交还给同样映射到该位置的其余节点
+	for _, peerName := range sortedPeers(r.peers) {
+		for i := 0; i < m.replicas*r.peers[peerName] && len(freed) > 0; i++ {
 			hashValue := int(m.hash([]byte(strconv.Itoa(i) + peerName)))
-			m.ring = append(m.ring, hashValue)
-			m.hashMap[hashValue] = peerName
+			if _, ok := freed[hashValue]; ok {
+				delete(freed, hashValue)
+				r.ring = append(r.ring, hashValue)
+				r.hashMap[hashValue] = peerName
+			}
+		}
+	}
+	sort.Ints(r.ring)
+	return true
+}
+
+// Weight 返回节点的权重，节点不在环上时返回 0
+func (m *Map) Weight(peer string) int {
+	return m.load().peers[peer]
+}
+
+// Peers 返回环上的所有节点，按名称排序
+func (m *Map) Peers() []string {
+	r := m.load()
+	peers := make([]string, 0, len(r.peers))
+	for peerName := range r.peers {
+		peers = append(peers, peerName)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// GetPeers 沿哈希环顺时针返回 key 之后的 n 个不同的节点
+// 有界负载模式不影响 GetPeers 的结果
+func (m *Map) GetPeers(key string, n int) []string {
+	r := m.load()
+	n = minInt(n, len(r.peers))
+	if n <= 0 {
+		return nil
+	}
+	hashValue := int(m.hash([]byte(key)))
+	idx := sort.Search(len(r.ring), func(i int) bool {
+		return r.ring[i] >= hashValue
+	})
+	peers := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for i := 0; i < len(r.ring) && len(peers) < n; i++ {
+		peerName := r.hashMap[r.ring[(idx+i)%len(r.ring)]]
+		if !seen[peerName] {
+			seen[peerName] = true
+			peers = append(peers, peerName)
 		}
 	}
-	sort.Ints(m.ring)
+	return peers
+}
+
+// SetBoundedLoad 开启有界负载模式 (Consistent Hashing with Bounded Loads)
+// 每个节点的负载上限是按权重折算后平均负载的 (1+epsilon) 倍，
+// GetPeer 会沿着哈希环顺时针跳过负载达到上限的节点。load 为 nil 时关闭该模式
+func (m *Map) SetBoundedLoad(epsilon float64, load LoadFunc) error {
+	if load == nil {
+		m.bound.Store(nil)
+		return nil
+	}
+	if epsilon <= 0 {
+		return fmt.Errorf("epsilon must be positive, got %v", epsilon)
+	}
+	m.bound.Store(&boundedLoad{epsilon: epsilon, load: load})
+	return nil
 }
 
 // GetPeer 根据 key 计算应当使用的节点
 func (m *Map) GetPeer(key string) string {
-	if len(m.ring) == 0 {
+	r := m.load()
+	if len(r.ring) == 0 {
 		return ""
 	}
 	hashValue := int(m.hash([]byte(key)))
-	idx := sort.Search(len(m.ring), func(i int) bool {
-		return m.ring[i] >= hashValue
+	idx := sort.Search(len(r.ring), func(i int) bool {
+		return r.ring[i] >= hashValue
 	})
-	return m.hashMap[m.ring[idx%len(m.ring)]]
+	if b := m.bound.Load(); b != nil {
+		return r.boundedPeer(idx, b)
+	}
+	return r.hashMap[r.ring[idx%len(r.ring)]]
+}
+
+// boundedPeer 从 idx 开始沿哈希环查找第一个负载未达到上限的节点
+// 节点 p 的上限为 ceil((total+1) * (1+epsilon) * weight(p) / totalWeight)
+// 总负载只计算一次，之后只查询沿途经过的节点的负载，不分配内存
+func (r *ring) boundedPeer(idx int, b *boundedLoad) string {
+	var total int64
+	for peerName := range r.peers {
+		total += b.load(peerName)
+	}
+	avg := float64(total+1) * (1 + b.epsilon) / float64(r.weight)
+	last := ""
+	for i := 0; i < len(r.ring); i++ {
+		peerName := r.hashMap[r.ring[(idx+i)%len(r.ring)]]
+		// 相邻的虚拟节点属于同一个节点时不必再次检查
+		if peerName == last {
+			continue
+		}
+		last = peerName
+		if float64(b.load(peerName)+1) <= math.Ceil(avg*float64(r.peers[peerName])) {
+			return peerName
+		}
+	}
+	return r.hashMap[r.ring[idx%len(r.ring)]]
 }
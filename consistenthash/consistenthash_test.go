@@ -3,20 +3,25 @@ package consistenthash
 import (
 	"hash/crc32"
 	"log"
+	"math"
+	"reflect"
 	"sort"
+	"strconv"
+	"sync"
 	"testing"
 )
 
 func TestRegister(t *testing.T) {
 	c := New(2, nil)
 	c.Registe("peer1", "peer2")
-	if len(c.ring) != 4 {
-		t.Errorf("got %d; expect %d", len(c.ring), 4)
+	r := c.load()
+	if len(r.ring) != 4 {
+		t.Errorf("got %d; expect %d", len(r.ring), 4)
 	}
 	hashValue := int(crc32.ChecksumIEEE([]byte("1peer1")))
-	idx := sort.SearchInts(c.ring, hashValue)
-	if c.ring[idx] != hashValue {
-		t.Errorf("got %d; expect %d", c.ring[idx], hashValue)
+	idx := sort.SearchInts(r.ring, hashValue)
+	if r.ring[idx] != hashValue {
+		t.Errorf("got %d; expect %d", r.ring[idx], hashValue)
 	}
 }
 
@@ -26,9 +31,150 @@ func TestGetPeer(t *testing.T) {
 	key := "TOM"
 	keyHashValue := int(crc32.ChecksumIEEE([]byte(key)))
 	log.Printf("key hash = %d", keyHashValue)
-	for _, v := range c.ring {
-		log.Printf("%d -> %s\n", v, c.hashMap[v])
+	r := c.load()
+	for _, v := range r.ring {
+		log.Printf("%d -> %s\n", v, r.hashMap[v])
 	}
 	peer := c.GetPeer(key)
 	log.Printf("Go to search -> %s\n", peer)
 }
+
+func TestRemove(t *testing.T) {
+	c := New(50, nil)
+	c.Registe("peer1", "peer2", "peer3")
+	owners := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		owners[key] = c.GetPeer(key)
+	}
+	old := c.load()
+
+	c.Remove("peer2")
+	if peers := c.Peers(); !reflect.DeepEqual(peers, []string{"peer1", "peer3"}) {
+		t.Fatalf("got %v; expect [peer1 peer3]", peers)
+	}
+	if len(old.ring) != 150 || len(c.load().ring) != 100 {
+		t.Fatalf("remove should not modify the old ring, got %d and %d", len(old.ring), len(c.load().ring))
+	}
+	for key, owner := range owners {
+		peer := c.GetPeer(key)
+		if peer == "peer2" {
+			t.Fatalf("%s should not be picked on removed peer", key)
+		}
+		if owner != "peer2" && peer != owner {
+			t.Fatalf("%s moved from %s to %s", key, owner, peer)
+		}
+	}
+
+	c.Registe("peer2")
+	for key, owner := range owners {
+		if peer := c.GetPeer(key); peer != owner {
+			t.Fatalf("%s should move back to %s, but got %s", key, owner, peer)
+		}
+	}
+}
+
+func TestRemoveCollision(t *testing.T) {
+	// 所有节点的第 i 个虚拟节点都映射到同一个位置
+	c := New(3, func(data []byte) uint32 {
+		i, _ := strconv.Atoi(string(data[:1]))
+		return uint32(i)
+	})
+	c.Registe("peer1", "peer2")
+	if r := c.load(); len(r.ring) != 3 || r.hashMap[0] != "peer1" {
+		t.Fatalf("collided slots should keep peer1, got %v", r.hashMap)
+	}
+	c.Remove("peer1")
+	r := c.load()
+	if len(r.ring) != 3 {
+		t.Fatalf("collided slots should be taken over by peer2, got %v", r.ring)
+	}
+	for _, hashValue := range r.ring {
+		if r.hashMap[hashValue] != "peer2" {
+			t.Fatalf("slot %d should belong to peer2, but got %s", hashValue, r.hashMap[hashValue])
+		}
+	}
+}
+
+func TestConcurrentGetPeer(t *testing.T) {
+	c := New(50, nil)
+	c.Registe("peer1")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if c.GetPeer(strconv.Itoa(j)) == "" {
+					t.Error("peer1 should always be on the ring")
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		c.Registe("peer2")
+		c.Remove("peer2")
+	}
+	wg.Wait()
+}
+
+func TestRegisteWithWeight(t *testing.T) {
+	c := New(50, nil)
+	c.Registe("small")
+	c.RegisteWithWeight(4, "large")
+	if len(c.load().ring) != 250 || c.Weight("large") != 4 {
+		t.Fatalf("got %d virtual nodes and weight %d; expect 250 and 4", len(c.load().ring), c.Weight("large"))
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[c.GetPeer(strconv.Itoa(i))]++
+	}
+	if counts["large"] < 2*counts["small"] {
+		t.Fatalf("large peer should own most keys, but got %v", counts)
+	}
+
+	c.RegisteWithWeight(1, "large")
+	if len(c.load().ring) != 100 || c.Weight("large") != 1 {
+		t.Fatalf("got %d virtual nodes and weight %d; expect 100 and 1", len(c.load().ring), c.Weight("large"))
+	}
+}
+
+func TestBoundedLoad(t *testing.T) {
+	c := New(50, nil)
+	c.Registe("peer1", "peer2", "peer3", "peer4")
+	if err := c.SetBoundedLoad(0, func(string) int64 { return 0 }); err == nil {
+		t.Fatal("non-positive epsilon should return an error")
+	}
+
+	// 模拟请求只增不减的情况，所有 key 都是同一个热点
+	loads := make(map[string]int64)
+	if err := c.SetBoundedLoad(0.25, func(peer string) int64 { return loads[peer] }); err != nil {
+		t.Fatal(err)
+	}
+	owner := c.GetPeer("hot")
+	for i := 0; i < 1000; i++ {
+		loads[c.GetPeer("hot")]++
+	}
+	limit := int64(math.Ceil(1000 * 1.25 / 4))
+	for peer, l := range loads {
+		if l > limit {
+			t.Fatalf("load of %s is %d, exceeds %d", peer, l, limit)
+		}
+	}
+	if loads[owner] != limit {
+		t.Fatalf("owner %s should be filled up to %d, but got %d", owner, limit, loads[owner])
+	}
+
+	bounded := testing.AllocsPerRun(100, func() { c.GetPeer("hot") })
+
+	c.SetBoundedLoad(0, nil)
+	if allocs := testing.AllocsPerRun(100, func() { c.GetPeer("hot") }); bounded != allocs {
+		t.Fatalf("bounded load should not allocate, but got %v allocs, want %v", bounded, allocs)
+	}
+	for i := 0; i < 10; i++ {
+		if peer := c.GetPeer("hot"); peer != owner {
+			t.Fatalf("bounded load disabled, want %s but got %s", owner, peer)
+		}
+	}
+}
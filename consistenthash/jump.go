@@ -0,0 +1,178 @@
+package consistenthash
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Jump 是 jump 一致性哈希 (Lamping & Veach)
+// 它不需要额外的内存，分布也非常均匀，但只能在末尾增删桶
+// 权重为 w 的节点占用 w 个桶。移除中间的节点时用最后一个桶填补空位，
+// 因此除了被移除节点的 key，最后一个桶的 key 也会移动
+type Jump struct {
+	mu sync.Mutex // mu 保证修改操作串行执行
+	s  atomic.Pointer[jumpState]
+}
+
+// jumpState 是某一时刻节点的快照，创建后不再修改
+type jumpState struct {
+	weights map[string]int
+	buckets []string // buckets[i] 是第 i 个桶所属的节点
+}
+
+// NewJump 返回一个没有节点的 Jump
+func NewJump() *Jump {
+	j := &Jump{}
+	j.s.Store(&jumpState{weights: make(map[string]int)})
+	return j
+}
+
+// Registe 将节点以权重 1 加入
+func (j *Jump) Registe(peers ...string) {
+	j.RegisteWithWeight(1, peers...)
+}
+
+// RegisteWithWeight 以 weight 加入节点，新的桶追加在末尾
+// 已经存在的节点会更新为新的权重
+func (j *Jump) RegisteWithWeight(weight int, peers ...string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.registe(peers, uniformWeights(weight, peers))
+}
+
+// RegisteWeights 以各自的权重加入多个节点，新的节点按名称顺序追加桶
+// 已经存在的节点会更新为新的权重
+func (j *Jump) RegisteWeights(weights map[string]int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.registe(sortedPeers(weights), weights)
+}
+
+// registe 按 peers 的顺序更新节点的桶，调用时需要持有锁
+func (j *Jump) registe(peers []string, weights map[string]int) {
+	old := j.s.Load()
+	s := &jumpState{
+		weights: copyWeights(old.weights),
+		buckets: append([]string(nil), old.buckets...),
+	}
+	changed := false
+	for _, peerName := range peers {
+		weight := weights[peerName]
+		if weight < 1 {
+			weight = 1
+		}
+		w := s.weights[peerName]
+		for ; w > weight; w-- {
+			s.removeBucket(peerName)
+		}
+		for ; w < weight; w++ {
+			s.buckets = append(s.buckets, peerName)
+		}
+		if s.weights[peerName] != weight {
+			s.weights[peerName] = weight
+			changed = true
+		}
+	}
+	if changed {
+		j.s.Store(s)
+	}
+}
+
+// Remove 移除节点
+func (j *Jump) Remove(peers ...string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	old := j.s.Load()
+	s := &jumpState{
+		weights: copyWeights(old.weights),
+		buckets: append([]string(nil), old.buckets...),
+	}
+	changed := false
+	for _, peerName := range peers {
+		w, ok := s.weights[peerName]
+		if !ok {
+			continue
+		}
+		for ; w > 0; w-- {
+			s.removeBucket(peerName)
+		}
+		delete(s.weights, peerName)
+		changed = true
+	}
+	if changed {
+		j.s.Store(s)
+	}
+}
+
+// removeBucket 移除节点的最后一个桶，并用末尾的桶填补空位
+func (s *jumpState) removeBucket(peerName string) {
+	for i := len(s.buckets) - 1; i >= 0; i-- {
+		if s.buckets[i] == peerName {
+			last := len(s.buckets) - 1
+			s.buckets[i] = s.buckets[last]
+			s.buckets = s.buckets[:last]
+			return
+		}
+	}
+}
+
+// Peers 返回所有节点，按名称排序
+func (j *Jump) Peers() []string {
+	return sortedPeers(j.s.Load().weights)
+}
+
+// Weight 返回节点的权重，节点不存在时返回 0
+func (j *Jump) Weight(peer string) int {
+	return j.s.Load().weights[peer]
+}
+
+// GetPeer 根据 key 计算应当使用的节点
+func (j *Jump) GetPeer(key string) string {
+	s := j.s.Load()
+	if len(s.buckets) == 0 {
+		return ""
+	}
+	return s.buckets[jumpHash(hash64(key), len(s.buckets))]
+}
+
+// GetPeers 返回 key 的 n 个不同的节点
+// 第一个节点与 GetPeer 相同，之后的节点依次使用加盐后的 key 计算，
+// 多次尝试仍不足 n 个时按桶的顺序补齐
+func (j *Jump) GetPeers(key string, n int) []string {
+	s := j.s.Load()
+	n = minInt(n, len(s.weights))
+	if n <= 0 {
+		return nil
+	}
+	peers := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	add := func(peerName string) {
+		if !seen[peerName] {
+			seen[peerName] = true
+			peers = append(peers, peerName)
+		}
+	}
+	kh := hash64(key)
+	add(s.buckets[jumpHash(kh, len(s.buckets))])
+	for i := 1; i < 4*len(s.buckets) && len(peers) < n; i++ {
+		add(s.buckets[jumpHash(mix64(kh+uint64(i)), len(s.buckets))])
+	}
+	for i := 0; len(peers) < n; i++ {
+		add(s.buckets[i])
+	}
+	return peers
+}
+
+// jumpHash 将 key 映射到 [0, n) 中的一个桶
+func jumpHash(key uint64, n int) int {
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+var _ Placement = (*Jump)(nil)
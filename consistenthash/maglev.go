@@ -0,0 +1,160 @@
+package consistenthash
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultMaglevSize 是 Maglev 查找表的默认大小，必须是质数
+const defaultMaglevSize = 65537
+
+// Maglev 是 Google Maglev 论文中的查找表哈希
+// 每次节点变化都会重新生成查找表，GetPeer 只需要一次查表
+// 节点间的负载几乎完全均衡，代价是节点变化时会有少量额外的 key 移动
+type Maglev struct {
+	size uint64     // size 是查找表的大小
+	mu   sync.Mutex // mu 保证修改操作串行执行
+	s    atomic.Pointer[maglevState]
+}
+
+// maglevState 是某一时刻节点的快照，创建后不再修改
+type maglevState struct {
+	weights map[string]int
+	peers   []string // peers 是按名称排序的节点
+	table   []int32  // table[i] 是查找表第 i 项对应的节点在 peers 中的下标
+}
+
+// NewMaglev 返回一个查找表大小为 size 的 Maglev，size 为 0 时使用 65537
+// size 必须是质数，并且应当远大于节点数，论文中建议至少是节点数的 100 倍
+func NewMaglev(size int) (*Maglev, error) {
+	if size == 0 {
+		size = defaultMaglevSize
+	}
+	if !isPrime(size) {
+		return nil, fmt.Errorf("maglev table size must be a prime, got %d", size)
+	}
+	m := &Maglev{size: uint64(size)}
+	m.s.Store(&maglevState{weights: make(map[string]int)})
+	return m, nil
+}
+
+// Registe 将节点以权重 1 加入
+func (m *Maglev) Registe(peers ...string) {
+	m.RegisteWithWeight(1, peers...)
+}
+
+// RegisteWithWeight 以 weight 加入节点，已经存在的节点会更新为新的权重
+func (m *Maglev) RegisteWithWeight(weight int, peers ...string) {
+	m.RegisteWeights(uniformWeights(weight, peers))
+}
+
+// RegisteWeights 以各自的权重加入多个节点，已经存在的节点会更新为新的权重
+func (m *Maglev) RegisteWeights(updates map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	weights := copyWeights(m.s.Load().weights)
+	if updateWeights(weights, updates) {
+		m.store(weights)
+	}
+}
+
+// Remove 移除节点
+func (m *Maglev) Remove(peers ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	weights := copyWeights(m.s.Load().weights)
+	if removeWeights(weights, peers) {
+		m.store(weights)
+	}
+}
+
+// store 根据 weights 生成新的查找表，调用时需要持有锁
+// 每个节点按照自己的排列依次填充空位，每一轮权重为 w 的节点填充 w 项
+func (m *Maglev) store(weights map[string]int) {
+	s := &maglevState{weights: weights, peers: sortedPeers(weights)}
+	if len(s.peers) > 0 {
+		n := len(s.peers)
+		offset := make([]uint64, n)
+		skip := make([]uint64, n)
+		next := make([]uint64, n)
+		for i, peerName := range s.peers {
+			h := hash64(peerName)
+			offset[i] = h % m.size
+			skip[i] = mix64(h^0x9e3779b97f4a7c15)%(m.size-1) + 1
+		}
+		s.table = make([]int32, m.size)
+		for i := range s.table {
+			s.table[i] = -1
+		}
+		for filled := uint64(0); filled < m.size; {
+			for i, peerName := range s.peers {
+				for w := 0; w < weights[peerName] && filled < m.size; w++ {
+					c := (offset[i] + next[i]*skip[i]) % m.size
+					for s.table[c] >= 0 {
+						next[i]++
+						c = (offset[i] + next[i]*skip[i]) % m.size
+					}
+					s.table[c] = int32(i)
+					next[i]++
+					filled++
+				}
+			}
+		}
+	}
+	m.s.Store(s)
+}
+
+// Peers 返回所有节点，按名称排序
+func (m *Maglev) Peers() []string {
+	return append([]string(nil), m.s.Load().peers...)
+}
+
+// Weight 返回节点的权重，节点不存在时返回 0
+func (m *Maglev) Weight(peer string) int {
+	return m.s.Load().weights[peer]
+}
+
+// GetPeer 根据 key 计算应当使用的节点
+func (m *Maglev) GetPeer(key string) string {
+	s := m.s.Load()
+	if len(s.table) == 0 {
+		return ""
+	}
+	return s.peers[s.table[hash64(key)%m.size]]
+}
+
+// GetPeers 从 key 在查找表中的位置开始依次向后查找，返回 n 个不同的节点
+func (m *Maglev) GetPeers(key string, n int) []string {
+	s := m.s.Load()
+	n = minInt(n, len(s.peers))
+	if n <= 0 {
+		return nil
+	}
+	peers := make([]string, 0, n)
+	seen := make(map[int32]bool, n)
+	start := hash64(key) % m.size
+	for i := uint64(0); i < m.size && len(peers) < n; i++ {
+		idx := s.table[(start+i)%m.size]
+		if !seen[idx] {
+			seen[idx] = true
+			peers = append(peers, s.peers[idx])
+		}
+	}
+	return peers
+}
+
+// isPrime 判断 n 是否是质数
+func isPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+	for i := 2; i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+var _ Placement = (*Maglev)(nil)
@@ -0,0 +1,142 @@
+package consistenthash
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 可选的放置算法
+const (
+	PlacementRing       = "ring"       // PlacementRing 是基于虚拟节点的一致性哈希环
+	PlacementRendezvous = "rendezvous" // PlacementRendezvous 是最高随机权重 (HRW) 哈希
+	PlacementJump       = "jump"       // PlacementJump 是 jump 一致性哈希
+	PlacementMaglev     = "maglev"     // PlacementMaglev 是 Maglev 查找表哈希
+)
+
+// Placement 决定 key 应当由哪个节点负责
+// 所有实现的 GetPeer 都可以与修改操作并发执行
+type Placement interface {
+	// Registe 将节点以权重 1 加入
+	Registe(peers ...string)
+	// RegisteWithWeight 以 weight 加入节点，已经存在的节点会更新为新的权重
+	RegisteWithWeight(weight int, peers ...string)
+	// RegisteWeights 一次加入多个节点，每个节点使用 weights 中各自的权重，只重建一次
+	// 已经存在的节点会更新为新的权重
+	RegisteWeights(weights map[string]int)
+	// Remove 移除节点
+	Remove(peers ...string)
+	// Peers 返回所有节点，按名称排序
+	Peers() []string
+	// Weight 返回节点的权重，节点不存在时返回 0
+	Weight(peer string) int
+	// GetPeer 根据 key 计算应当使用的节点，没有节点时返回空字符串
+	GetPeer(key string) string
+	// GetPeers 按优先级返回 key 的前 n 个不同的节点，不考虑有界负载时第一个与 GetPeer 的结果相同
+	// 节点数不足 n 时返回所有节点
+	GetPeers(key string, n int) []string
+}
+
+// NewPlacement 根据名称创建放置算法，replicas 只对 PlacementRing 生效
+func NewPlacement(name string, replicas int) (Placement, error) {
+	switch name {
+	case "", PlacementRing:
+		return New(replicas, nil), nil
+	case PlacementRendezvous:
+		return NewRendezvous(), nil
+	case PlacementJump:
+		return NewJump(), nil
+	case PlacementMaglev:
+		return NewMaglev(0)
+	}
+	return nil, fmt.Errorf("unknown placement %q", name)
+}
+
+// uniformWeights 返回所有节点的权重都是 weight 的 map
+func uniformWeights(weight int, peers []string) map[string]int {
+	weights := make(map[string]int, len(peers))
+	for _, peerName := range peers {
+		weights[peerName] = weight
+	}
+	return weights
+}
+
+// updateWeights 根据 updates 更新 weights，小于 1 的权重按 1 处理，返回 weights 是否发生变化
+func updateWeights(weights map[string]int, updates map[string]int) bool {
+	changed := false
+	for peerName, weight := range updates {
+		if weight < 1 {
+			weight = 1
+		}
+		if weights[peerName] != weight {
+			weights[peerName] = weight
+			changed = true
+		}
+	}
+	return changed
+}
+
+// removeWeights 从 weights 中删除节点，返回 weights 是否发生变化
+func removeWeights(weights map[string]int, peers []string) bool {
+	changed := false
+	for _, peerName := range peers {
+		if _, ok := weights[peerName]; ok {
+			delete(weights, peerName)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// copyWeights 复制 weights
+func copyWeights(weights map[string]int) map[string]int {
+	m := make(map[string]int, len(weights))
+	for k, v := range weights {
+		m[k] = v
+	}
+	return m
+}
+
+// minInt 返回 a 和 b 中较小的一个
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// sortedPeers 返回按名称排序的节点
+func sortedPeers(weights map[string]int) []string {
+	peers := make([]string, 0, len(weights))
+	for peerName := range weights {
+		peers = append(peers, peerName)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+const (
+	offset64 = 14695981039346656037
+	prime64  = 1099511628211
+)
+
+// hash64 使用 FNV-1a 计算字符串的 64 位哈希值，并打散低位的相关性
+func hash64(s string) uint64 {
+	h := uint64(offset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime64
+	}
+	return mix64(h)
+}
+
+// mix64 是 MurmurHash3 的 fmix64，使输入的每一位都影响输出的所有位
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+var _ Placement = (*Map)(nil)
@@ -0,0 +1,237 @@
+package consistenthash
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+const placementKeys = 100000
+
+// newPlacements 返回所有放置算法
+func newPlacements(t *testing.T) map[string]Placement {
+	t.Helper()
+	placements := make(map[string]Placement)
+	for _, name := range []string{PlacementRing, PlacementRendezvous, PlacementJump, PlacementMaglev} {
+		p, err := NewPlacement(name, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		placements[name] = p
+	}
+	return placements
+}
+
+// assign 返回每个 key 所属的节点
+func assign(p Placement) []string {
+	owners := make([]string, placementKeys)
+	for i := range owners {
+		owners[i] = p.GetPeer("key" + strconv.Itoa(i))
+	}
+	return owners
+}
+
+// imbalance 返回负载最高的节点与平均负载的比值，weights 为 nil 时所有节点权重相同
+func imbalance(owners []string, peers []string, weights map[string]int) float64 {
+	counts := make(map[string]int)
+	for _, owner := range owners {
+		counts[owner]++
+	}
+	total := 0
+	for _, peer := range peers {
+		total += weightOr1(weights, peer)
+	}
+	max := 0.0
+	for _, peer := range peers {
+		expect := float64(len(owners)) * float64(weightOr1(weights, peer)) / float64(total)
+		if r := float64(counts[peer]) / expect; r > max {
+			max = r
+		}
+	}
+	return max
+}
+
+func weightOr1(weights map[string]int, peer string) int {
+	if w, ok := weights[peer]; ok {
+		return w
+	}
+	return 1
+}
+
+// moved 返回归属发生变化的 key 的比例
+func moved(before, after []string) float64 {
+	n := 0
+	for i := range before {
+		if before[i] != after[i] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(before))
+}
+
+func peerNames(n int) []string {
+	peers := make([]string, n)
+	for i := range peers {
+		peers[i] = fmt.Sprintf("10.0.0.%d:6324", i)
+	}
+	return peers
+}
+
+func TestNewPlacement(t *testing.T) {
+	if _, err := NewPlacement("unknown", 50); err == nil {
+		t.Fatal("unknown placement should return an error")
+	}
+	if _, err := NewMaglev(65536); err == nil {
+		t.Fatal("maglev size which is not a prime should return an error")
+	}
+	for name, p := range newPlacements(t) {
+		if peer := p.GetPeer("Tom"); peer != "" {
+			t.Errorf("%s: empty placement should return no peer, but got %s", name, peer)
+		}
+	}
+}
+
+func TestPlacementBalance(t *testing.T) {
+	// 最高负载与平均负载之比的上限，CRC32 哈希环在节点较少时分布较差
+	limits := map[string]float64{
+		PlacementRing:       2,
+		PlacementRendezvous: 1.05,
+		PlacementJump:       1.05,
+		PlacementMaglev:     1.05,
+	}
+	peers := peerNames(10)
+	for name, p := range newPlacements(t) {
+		p.Registe(peers...)
+		r := imbalance(assign(p), peers, nil)
+		t.Logf("%s: max/avg load = %.3f", name, r)
+		if r > limits[name] {
+			t.Errorf("%s: max/avg load %.3f exceeds %.2f", name, r, limits[name])
+		}
+	}
+}
+
+func TestPlacementWeightedBalance(t *testing.T) {
+	peers := peerNames(4)
+	weights := map[string]int{peers[0]: 1, peers[1]: 2, peers[2]: 4, peers[3]: 8}
+	for name, p := range newPlacements(t) {
+		for peer, w := range weights {
+			p.RegisteWithWeight(w, peer)
+		}
+		r := imbalance(assign(p), peers, weights)
+		t.Logf("%s: weighted max/avg load = %.3f", name, r)
+		if r > 1.5 {
+			t.Errorf("%s: weighted max/avg load %.3f exceeds 1.5", name, r)
+		}
+	}
+}
+
+// TestRegisteWeights 检查一次加入多个节点与逐个加入的结果相同
+func TestRegisteWeights(t *testing.T) {
+	peers := peerNames(4)
+	weights := map[string]int{peers[0]: 1, peers[1]: 2, peers[2]: 0, peers[3]: 8}
+	batch, single := newPlacements(t), newPlacements(t)
+	for name, p := range batch {
+		p.RegisteWeights(weights)
+		for _, peer := range peers {
+			single[name].RegisteWithWeight(weights[peer], peer)
+		}
+		for _, peer := range peers {
+			if w, want := p.Weight(peer), single[name].Weight(peer); w != want {
+				t.Errorf("%s: weight of %s is %d, want %d", name, peer, w, want)
+			}
+		}
+		if name == PlacementJump {
+			// jump 的桶与加入的顺序有关
+			continue
+		}
+		for i := 0; i < 1000; i++ {
+			key := strconv.Itoa(i)
+			if got, want := p.GetPeer(key), single[name].GetPeer(key); got != want {
+				t.Fatalf("%s: %s is placed on %s, want %s", name, key, got, want)
+			}
+		}
+	}
+}
+
+func TestPlacementMovement(t *testing.T) {
+	// 节点变化时移动的 key 的比例上限，最优值是 1/11 和 1/10
+	addLimits := map[string]float64{
+		PlacementRing:       0.15,
+		PlacementRendezvous: 0.1,
+		PlacementJump:       0.1,
+		PlacementMaglev:     0.15,
+	}
+	removeLimits := map[string]float64{
+		PlacementRing:       0.15,
+		PlacementRendezvous: 0.11,
+		PlacementJump:       0.21,
+		PlacementMaglev:     0.15,
+	}
+	peers := peerNames(11)
+	for name, p := range newPlacements(t) {
+		p.Registe(peers[:10]...)
+		before := assign(p)
+
+		p.Registe(peers[10])
+		added := assign(p)
+		if m := moved(before, added); m > addLimits[name] {
+			t.Errorf("%s: %.3f keys moved when adding a peer, exceeds %.2f", name, m, addLimits[name])
+		} else {
+			t.Logf("%s: %.3f keys moved when adding a peer", name, m)
+		}
+		// Maglev 重新生成查找表时，少量 key 会在旧节点之间移动
+		for i := range before {
+			if name != PlacementMaglev && before[i] != added[i] && added[i] != peers[10] {
+				t.Fatalf("%s: key moved from %s to %s which is not the new peer", name, before[i], added[i])
+			}
+		}
+
+		p.Remove(peers[3])
+		removed := assign(p)
+		if m := moved(added, removed); m > removeLimits[name] {
+			t.Errorf("%s: %.3f keys moved when removing a peer, exceeds %.2f", name, m, removeLimits[name])
+		} else {
+			t.Logf("%s: %.3f keys moved when removing a peer", name, m)
+		}
+		for _, owner := range removed {
+			if owner == peers[3] {
+				t.Fatalf("%s: key should not be owned by removed peer", name)
+			}
+		}
+	}
+}
+
+func BenchmarkGetPeer(b *testing.B) {
+	for _, name := range []string{PlacementRing, PlacementRendezvous, PlacementJump, PlacementMaglev} {
+		p, _ := NewPlacement(name, 50)
+		p.Registe(peerNames(10)...)
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p.GetPeer("key" + strconv.Itoa(i&1023))
+			}
+		})
+	}
+}
+
+func TestPlacementGetPeers(t *testing.T) {
+	peers := peerNames(5)
+	for name, p := range newPlacements(t) {
+		if got := p.GetPeers("Tom", 3); len(got) != 0 {
+			t.Errorf("%s: empty placement should return no peers, but got %v", name, got)
+		}
+		p.Registe(peers...)
+		for i := 0; i < 100; i++ {
+			key := "key" + strconv.Itoa(i)
+			got := p.GetPeers(key, 3)
+			if len(got) != 3 || got[0] != p.GetPeer(key) {
+				t.Fatalf("%s: want 3 peers starting with %s, but got %v", name, p.GetPeer(key), got)
+			}
+			if got[0] == got[1] || got[0] == got[2] || got[1] == got[2] {
+				t.Fatalf("%s: peers should be distinct, but got %v", name, got)
+			}
+		}
+		if got := p.GetPeers("Tom", 10); len(got) != len(peers) {
+			t.Errorf("%s: want all %d peers, but got %v", name, len(peers), got)
+		}
+	}
+}
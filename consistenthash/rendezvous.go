@@ -0,0 +1,133 @@
+package consistenthash
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Rendezvous 是最高随机权重 (HRW) 哈希
+// 每个 key 对每个节点计算一个分数，分数最高的节点负责该 key
+// 节点变化时只有归属于该节点的 key 会移动，GetPeer 的开销与节点数成正比
+type Rendezvous struct {
+	mu sync.Mutex // mu 保证修改操作串行执行
+	s  atomic.Pointer[rendezvousState]
+}
+
+// rendezvousState 是某一时刻节点的快照，创建后不再修改
+type rendezvousState struct {
+	weights map[string]int
+	nodes   []rendezvousNode
+}
+
+type rendezvousNode struct {
+	name   string
+	hash   uint64
+	weight float64
+}
+
+// NewRendezvous 返回一个没有节点的 Rendezvous
+func NewRendezvous() *Rendezvous {
+	r := &Rendezvous{}
+	r.s.Store(&rendezvousState{weights: make(map[string]int)})
+	return r
+}
+
+// Registe 将节点以权重 1 加入
+func (r *Rendezvous) Registe(peers ...string) {
+	r.RegisteWithWeight(1, peers...)
+}
+
+// RegisteWithWeight 以 weight 加入节点，已经存在的节点会更新为新的权重
+func (r *Rendezvous) RegisteWithWeight(weight int, peers ...string) {
+	r.RegisteWeights(uniformWeights(weight, peers))
+}
+
+// RegisteWeights 以各自的权重加入多个节点，已经存在的节点会更新为新的权重
+func (r *Rendezvous) RegisteWeights(updates map[string]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	weights := copyWeights(r.s.Load().weights)
+	if updateWeights(weights, updates) {
+		r.store(weights)
+	}
+}
+
+// Remove 移除节点
+func (r *Rendezvous) Remove(peers ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	weights := copyWeights(r.s.Load().weights)
+	if removeWeights(weights, peers) {
+		r.store(weights)
+	}
+}
+
+// store 根据 weights 生成新的快照，调用时需要持有锁
+func (r *Rendezvous) store(weights map[string]int) {
+	s := &rendezvousState{weights: weights}
+	for _, peerName := range sortedPeers(weights) {
+		s.nodes = append(s.nodes, rendezvousNode{
+			name:   peerName,
+			hash:   hash64(peerName),
+			weight: float64(weights[peerName]),
+		})
+	}
+	r.s.Store(s)
+}
+
+// Peers 返回所有节点，按名称排序
+func (r *Rendezvous) Peers() []string {
+	return sortedPeers(r.s.Load().weights)
+}
+
+// Weight 返回节点的权重，节点不存在时返回 0
+func (r *Rendezvous) Weight(peer string) int {
+	return r.s.Load().weights[peer]
+}
+
+// GetPeer 返回分数最高的节点
+func (r *Rendezvous) GetPeer(key string) string {
+	s := r.s.Load()
+	kh := hash64(key)
+	best, bestScore := "", math.Inf(-1)
+	for _, n := range s.nodes {
+		if score := n.score(kh); score > bestScore {
+			best, bestScore = n.name, score
+		}
+	}
+	return best
+}
+
+// GetPeers 返回分数最高的 n 个节点
+func (r *Rendezvous) GetPeers(key string, n int) []string {
+	s := r.s.Load()
+	n = minInt(n, len(s.nodes))
+	if n <= 0 {
+		return nil
+	}
+	kh := hash64(key)
+	scores := make([]float64, len(s.nodes))
+	idx := make([]int, len(s.nodes))
+	for i, node := range s.nodes {
+		scores[i], idx[i] = node.score(kh), i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		return scores[idx[i]] > scores[idx[j]]
+	})
+	peers := make([]string, n)
+	for i := range peers {
+		peers[i] = s.nodes[idx[i]].name
+	}
+	return peers
+}
+
+// score 返回节点对 key 的分数
+// 带权重的分数为 -weight/ln(u)，u 是 key 和节点的哈希值映射到 (0, 1) 的结果
+func (n rendezvousNode) score(kh uint64) float64 {
+	u := (float64(mix64(kh^n.hash)>>11) + 0.5) / (1 << 53)
+	return -n.weight / math.Log(u)
+}
+
+var _ Placement = (*Rendezvous)(nil)
@@ -0,0 +1,107 @@
+package pcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	pb "pcache/pcachepb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrNotFound 表示数据源中不存在该 key
+	// Getter 返回包装了 ErrNotFound 的错误时，Group 不会再尝试其他来源
+	ErrNotFound = errors.New("pcache: key not found")
+	// ErrGroupNotFound 表示节点中没有对应名字的 Group
+	ErrGroupNotFound = errors.New("pcache: group not found")
+	// ErrPeerUnavailable 表示远程节点无法连接
+	ErrPeerUnavailable = errors.New("pcache: peer unavailable")
+	// ErrKeyRequired 表示请求的 key 为空
+	ErrKeyRequired = errors.New("pcache: key is required")
+	// ErrNotSupported 表示远程节点的 Fetcher 不支持该操作，例如没有实现 Mutator
+	ErrNotSupported = errors.New("pcache: operation not supported by peer")
+)
+
+// toStatus 将错误转换为 gRPC 状态，使错误类型可以跨节点传递
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	var code codes.Code
+	switch {
+	case errors.Is(err, ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, ErrGroupNotFound):
+		code = codes.FailedPrecondition
+	case errors.Is(err, ErrKeyRequired):
+		code = codes.InvalidArgument
+	case errors.Is(err, ErrPeerUnavailable):
+		code = codes.Unavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	default:
+		code = codes.Unknown
+	}
+	return status.Error(code, err.Error())
+}
+
+// toItem 将 key 的结果转换为 GetMulti 响应中的 Item
+func toItem(r Result) *pb.Item {
+	if r.Err != nil {
+		s := status.Convert(toStatus(r.Err))
+		return &pb.Item{Code: int32(s.Code()), Message: s.Message()}
+	}
+	item := &pb.Item{Value: r.Value.ByteSlice()}
+	if !r.Value.Expire().IsZero() {
+		item.Expire = r.Value.Expire().UnixNano()
+	}
+	return item
+}
+
+// fromItem 将 GetMulti 响应中失败的 Item 还原为对应的错误，与 toItem 相对应
+func fromItem(item *pb.Item) error {
+	return fromStatus(status.Error(codes.Code(item.GetCode()), item.GetMessage()))
+}
+
+// fromStatus 将 gRPC 状态还原为对应的错误，与 toStatus 相对应
+// 还原的错误保留远程节点的错误信息，无法识别的状态原样返回
+func fromStatus(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch s.Code() {
+	case codes.NotFound:
+		return wrapStatus(ErrNotFound, s)
+	case codes.FailedPrecondition:
+		return wrapStatus(ErrGroupNotFound, s)
+	case codes.InvalidArgument:
+		// 只有 key 为空时才还原为 ErrKeyRequired，其他的参数错误原样返回
+		if strings.Contains(s.Message(), ErrKeyRequired.Error()) {
+			return wrapStatus(ErrKeyRequired, s)
+		}
+		return err
+	case codes.Unavailable:
+		return wrapStatus(ErrPeerUnavailable, s)
+	case codes.DeadlineExceeded:
+		return wrapStatus(context.DeadlineExceeded, s)
+	case codes.Canceled:
+		return wrapStatus(context.Canceled, s)
+	default:
+		return err
+	}
+}
+
+// wrapStatus 返回包装了 target 和状态信息的错误，信息与 target 相同时直接返回 target
+func wrapStatus(target error, s *status.Status) error {
+	if s.Message() == "" || s.Message() == target.Error() {
+		return target
+	}
+	return fmt.Errorf("%w: %s", target, s.Message())
+}
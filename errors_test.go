@@ -0,0 +1,65 @@
+package pcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusRoundTrip(t *testing.T) {
+	for _, want := range []error{
+		ErrNotFound, ErrGroupNotFound, ErrPeerUnavailable, ErrKeyRequired,
+		context.DeadlineExceeded, context.Canceled,
+	} {
+		err := fromStatus(toStatus(fmt.Errorf("wrapped: %w", want)))
+		if !errors.Is(err, want) {
+			t.Errorf("want %v after round trip, but got %v", want, err)
+		}
+	}
+	err := fromStatus(toStatus(fmt.Errorf("no key Tom: %w", ErrNotFound)))
+	if !strings.Contains(err.Error(), "no key Tom") {
+		t.Errorf("remote message should be kept, but got %v", err)
+	}
+	err = fromStatus(status.Error(codes.InvalidArgument, "bad group name"))
+	if errors.Is(err, ErrKeyRequired) {
+		t.Errorf("other invalid argument should not be ErrKeyRequired, but got %v", err)
+	}
+	if err := toStatus(nil); err != nil {
+		t.Fatalf("nil error should stay nil, but got %v", err)
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	g := NewGroup("error-test", 0, GetterFunc(func(key string) ([]byte, error) {
+		return nil, fmt.Errorf("no key %s: %w", key, ErrNotFound)
+	}))
+	if _, err := g.Get("Tom"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, but got %v", err)
+	}
+	if _, err := g.Get(""); !errors.Is(err, ErrKeyRequired) {
+		t.Fatalf("want ErrKeyRequired, but got %v", err)
+	}
+}
+
+func TestPeerNotFound(t *testing.T) {
+	loads := 0
+	owner := &fakePeer{NewGroup("peer-error-test-owner", 0, GetterFunc(func(key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))}
+	g := NewGroup("peer-error-test", 0, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("source"), nil
+	}))
+	g.RegisterPicker(&fakePicker{owner: owner})
+	if _, err := g.Get("remote"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, but got %v", err)
+	}
+	if loads != 0 {
+		t.Fatalf("not found from owner should not fall back to local source, but loaded %v times", loads)
+	}
+}
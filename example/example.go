@@ -16,7 +16,7 @@ var mysql = map[string]string{
 func GetFromMysql(key string) ([]byte, error) {
 	value, ok := mysql[key]
 	if !ok {
-		return []byte{}, fmt.Errorf("no key %v", key)
+		return []byte{}, fmt.Errorf("no key %v: %w", key, pcache.ErrNotFound)
 	}
 	return []byte(value), nil
 }
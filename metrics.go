@@ -0,0 +1,209 @@
+package pcache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBuckets 是延迟直方图的默认分桶，单位为秒
+var defaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	// serverGetLatency 统计 server.Get 的处理延迟
+	serverGetLatency = newHistogram(defaultBuckets)
+	// clientFetchLatency 统计 client.Fetch 的请求延迟
+	clientFetchLatency = newHistogram(defaultBuckets)
+)
+
+// histogram 是 Prometheus 格式的直方图
+type histogram struct {
+	mu     sync.Mutex
+	bounds []float64 // bounds 是每个桶的上界
+	counts []uint64  // counts 是落在每个桶中的次数，最后一个是 +Inf
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// observe 记录一次耗时
+func (h *histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	idx := sort.SearchFloat64s(h.bounds, v)
+	h.mu.Lock()
+	h.counts[idx]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// since 记录从 start 开始的耗时，通常配合 defer 使用
+func (h *histogram) since(start time.Time) {
+	h.observe(time.Since(start))
+}
+
+// write 以 Prometheus 文本格式输出直方图
+func (h *histogram) write(w io.Writer, name, help string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, bound, cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %g\n", name, h.sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// metric 是一个待输出的指标
+type metric struct {
+	name, help, kind string
+	samples          []sample
+}
+
+// sample 是指标的一个样本
+type sample struct {
+	labels string // labels 是格式化之后的标签，例如 {group="score"}
+	value  int64
+}
+
+func (m *metric) add(value int64, labels ...string) {
+	m.samples = append(m.samples, sample{labels: formatLabels(labels...), value: value})
+}
+
+func (m *metric) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+	for _, s := range m.samples {
+		fmt.Fprintf(w, "%s%s %d\n", m.name, s.labels, s.value)
+	}
+}
+
+// formatLabels 将 name, value 交替的参数格式化为 Prometheus 标签
+func formatLabels(kv ...string) string {
+	if len(kv) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", kv[i], labelEscaper.Replace(kv[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeGroupMetrics 输出所有 Group 的统计信息和按淘汰策略汇总的淘汰次数
+func writeGroupMetrics(w io.Writer) {
+	mu.RLock()
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	mu.RUnlock()
+	sort.Strings(names)
+
+	counter := func(name, help string) *metric {
+		return &metric{name: name, help: help, kind: "counter"}
+	}
+	gauge := func(name, help string) *metric {
+		return &metric{name: name, help: help, kind: "gauge"}
+	}
+	var (
+		gets          = counter("pcache_group_gets_total", "Number of Get requests.")
+		hits          = counter("pcache_group_hits_total", "Number of Get requests served from main or hot cache.")
+		misses        = counter("pcache_group_misses_total", "Number of Get requests that missed the cache.")
+		negativeHits  = counter("pcache_group_negative_hits_total", "Number of Get requests served from the negative cache.")
+		peerLoads     = counter("pcache_group_peer_loads_total", "Number of successful loads from remote peers.")
+		peerErrors    = counter("pcache_group_peer_errors_total", "Number of failed loads from remote peers.")
+		localLoads    = counter("pcache_group_local_loads_total", "Number of successful loads from the data source.")
+		localLoadErrs = counter("pcache_group_local_load_errors_total", "Number of failed loads from the data source.")
+		dedups        = counter("pcache_group_dedups_total", "Number of loads deduplicated by singleflight.")
+		batches       = counter("pcache_group_batches_total", "Number of coalesced calls to the batch getter.")
+		cacheBytes    = gauge("pcache_cache_bytes", "Bytes held by the cache.")
+		cacheItems    = gauge("pcache_cache_items", "Items held by the cache.")
+		cacheGets     = counter("pcache_cache_gets_total", "Number of cache lookups.")
+		cacheHits     = counter("pcache_cache_hits_total", "Number of cache hits.")
+		cacheEvicts   = counter("pcache_cache_evictions_total", "Number of entries evicted by capacity limits.")
+		policyEvicts  = counter("pcache_policy_evictions_total", "Number of entries evicted, by eviction policy.")
+	)
+	byPolicy := make(map[string]int64)
+	for _, name := range names {
+		g := GetGroup(name)
+		if g == nil {
+			continue
+		}
+		stats := g.Stats()
+		gets.add(stats.Gets, "group", name)
+		hits.add(stats.Hits, "group", name)
+		misses.add(stats.Misses, "group", name)
+		negativeHits.add(stats.NegativeHits, "group", name)
+		peerLoads.add(stats.PeerLoads, "group", name)
+		peerErrors.add(stats.PeerErrors, "group", name)
+		localLoads.add(stats.LocalLoads, "group", name)
+		localLoadErrs.add(stats.LocalLoadErrs, "group", name)
+		dedups.add(stats.Dedups, "group", name)
+		batches.add(stats.Batches, "group", name)
+		for _, c := range []struct {
+			name  string
+			cache *cache
+			stats CacheStats
+		}{
+			{"main", g.mainCache, stats.MainCache},
+			{"hot", g.hotCache, stats.HotCache},
+		} {
+			if c.cache == nil {
+				continue
+			}
+			cacheBytes.add(c.stats.Bytes, "group", name, "cache", c.name)
+			cacheItems.add(c.stats.Items, "group", name, "cache", c.name)
+			cacheGets.add(c.stats.Gets, "group", name, "cache", c.name)
+			cacheHits.add(c.stats.Hits, "group", name, "cache", c.name)
+			cacheEvicts.add(c.stats.Evictions, "group", name, "cache", c.name)
+			byPolicy[c.cache.policy] += c.stats.Evictions
+		}
+	}
+	policies := make([]string, 0, len(byPolicy))
+	for policy := range byPolicy {
+		policies = append(policies, policy)
+	}
+	sort.Strings(policies)
+	for _, policy := range policies {
+		policyEvicts.add(byPolicy[policy], "policy", policy)
+	}
+	for _, m := range []*metric{
+		gets, hits, misses, negativeHits, peerLoads, peerErrors, localLoads, localLoadErrs, dedups, batches,
+		cacheBytes, cacheItems, cacheGets, cacheHits, cacheEvicts, policyEvicts,
+	} {
+		m.write(w)
+	}
+}
+
+// MetricsHandler 返回以 Prometheus 文本格式输出指标的 http.Handler
+func (s *server) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeGroupMetrics(w)
+		serverGetLatency.write(w, "pcache_server_get_duration_seconds", "Latency of server Get RPCs.")
+		clientFetchLatency.write(w, "pcache_client_fetch_duration_seconds", "Latency of client Fetch RPCs.")
+		members := &metric{name: "pcache_ring_members", help: "Number of peers in the hash ring.", kind: "gauge"}
+		members.add(int64(s.ringSize()), "addr", s.addr)
+		members.write(w)
+	})
+}
@@ -0,0 +1,59 @@
+package pcache
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	g, err := NewGroupWithOptions("metrics-test", GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), GroupOptions{Policy: "lfu", MaxEntries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Get("k1")
+	g.Get("k2")
+	s, _ := NewServer("127.0.0.1:6324")
+	s.SetPeers("127.0.0.1:6324", "127.0.0.1:6325")
+
+	rec := httptest.NewRecorder()
+	s.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`pcache_group_gets_total{group="metrics-test"} 2`,
+		`pcache_cache_items{group="metrics-test",cache="main"} 1`,
+		`pcache_cache_evictions_total{group="metrics-test",cache="main"} 1`,
+		`pcache_policy_evictions_total{policy="lfu"} `,
+		`# TYPE pcache_server_get_duration_seconds histogram`,
+		`# TYPE pcache_client_fetch_duration_seconds histogram`,
+		`pcache_ring_members{addr="127.0.0.1:6324"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics should contain %q", want)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]float64{.001, .01})
+	h.observe(500 * time.Microsecond)
+	h.observe(5 * time.Millisecond)
+	h.observe(time.Second)
+	var buf bytes.Buffer
+	h.write(&buf, "latency", "test latency.")
+	want := `# HELP latency test latency.
+# TYPE latency histogram
+latency_bucket{le="0.001"} 1
+latency_bucket{le="0.01"} 2
+latency_bucket{le="+Inf"} 3
+latency_sum 1.0055
+latency_count 3
+`
+	if buf.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -1,10 +1,16 @@
 package pcache
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"pcache/purgekit"
 	"pcache/singleflight"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Getter 接口包含一个从数据源获取数据的 Get 方法
@@ -19,15 +25,121 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
 
+// ContextGetter 是可以感知 context 的 Getter
+// Group 会优先使用 GetContext，使调用方的超时和取消能够传递到数据源
+type ContextGetter interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+// ContextGetterFunc 同时实现了 Getter 和 ContextGetter 接口
+type ContextGetterFunc func(ctx context.Context, key string) ([]byte, error)
+
+func (f ContextGetterFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+func (f ContextGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// ExpireGetter 允许数据源为每个 key 指定过期时间
+// 返回的过期时间为零值时使用 Group 的默认 TTL
+type ExpireGetter interface {
+	GetWithExpire(ctx context.Context, key string) ([]byte, time.Time, error)
+}
+
+// ExpireGetterFunc 实现了 Getter、ContextGetter 和 ExpireGetter 接口
+type ExpireGetterFunc func(ctx context.Context, key string) ([]byte, time.Time, error)
+
+func (f ExpireGetterFunc) Get(key string) ([]byte, error) {
+	return f.GetContext(context.Background(), key)
+}
+
+func (f ExpireGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	bytes, _, err := f(ctx, key)
+	return bytes, err
+}
+
+func (f ExpireGetterFunc) GetWithExpire(ctx context.Context, key string) ([]byte, time.Time, error) {
+	return f(ctx, key)
+}
+
 // Group 提供了用户的交互入口
 type Group struct {
-	name      string               // name 是当前 Group 的名字
-	getter    Getter               // getter 从数据源获得数据
-	mainCache *cache               // mainCache 是真正的缓存
-	server    Picker               // server 从注册节点中选择节点
-	flight    *singleflight.Flight // flight 确保一个键同时只有一次请求
+	name        string               // name 是当前 Group 的名字
+	getter      Getter               // getter 从数据源获得数据
+	mainCache   *cache               // mainCache 是真正的缓存，保存当前节点负责的 key
+	hotCache    *cache               // hotCache 保存远程节点负责的热点 key，nil 表示不启用
+	hotRate     float64              // hotRate 是远程获取的值写入 hotCache 的概率
+	missCache   *cache               // missCache 保存数据源中不存在的 key，nil 表示不启用
+	missTTL     time.Duration        // missTTL 是 missCache 中条目的过期时间
+	server      Picker               // server 从注册节点中选择节点
+	flight      *singleflight.Flight // flight 确保一个键同时只有一次请求
+	ttl         time.Duration        // ttl 是缓存的默认过期时间，0 表示永不过期
+	replicas    int                  // replicas 是读取时依次尝试的节点数，包含所属节点
+	timeout     time.Duration        // timeout 是一次共享加载的超时时间
+	peerTimeout time.Duration        // peerTimeout 是一次访问远程节点的超时时间
+	batcher     *batcher             // batcher 合并并发的未命中，nil 表示不合并
+	stats       groupStats           // stats 是 Group 的统计计数器
+	versions    keyVersions          // versions 用于丢弃在 Set 和 Remove 之前开始的加载的结果
 }
 
+// GroupOptions 是创建 Group 时的可选配置，零值表示使用默认行为
+type GroupOptions struct {
+	Policy     string        // Policy 是缓存的淘汰策略，可选 lru、lfu、arc、tinylfu，默认为 lru，tinylfu 设置了 MaxBytes 时需要同时设置 MaxEntries
+	MaxEntries int           // MaxEntries 是缓存的最大条目数，0 表示不限制
+	MaxBytes   int64         // MaxBytes 是缓存的最大字节数（key 与 value 的长度之和，不包括 TypedGroup 解码后的对象），0 表示不限制
+	TTL        time.Duration // TTL 是缓存的默认过期时间，0 表示永不过期
+
+	// HotCacheMaxEntries 和 HotCacheMaxBytes 限制 hotCache 的大小
+	// 二者均为 0 时不启用 hotCache
+	HotCacheMaxEntries int
+	HotCacheMaxBytes   int64
+	// HotCacheRate 是从远程节点获取的值写入 hotCache 的概率，默认为 defaultHotCacheRate
+	HotCacheRate float64
+
+	// NegativeTTL 大于 0 时，数据源返回 ErrNotFound 的 key 会被缓存 NegativeTTL 的时间
+	// 期间对该 key 的请求直接返回 ErrNotFound，不会访问远程节点和数据源
+	NegativeTTL time.Duration
+	// NegativeMaxEntries 是缓存不存在的 key 的最大数量，默认为 defaultNegativeMaxEntries
+	NegativeMaxEntries int
+
+	// Replication 是读取时依次尝试的节点数，包含所属节点，0 和 1 表示只读取所属节点
+	// 大于 1 时需要 Picker 实现 ReplicaPicker，所属节点失败后会依次尝试后继节点，最后才回源
+	Replication int
+
+	// BatchWindow 大于 0 且 getter 实现了 BatchGetter 时，等待 BatchWindow 的时间合并并发的未命中
+	// 0 表示不合并，每个 key 单独从数据源获取。合并后的 key 通过 GetBatch 获取，
+	// 即使 getter 同时实现了 ExpireGetter，这些 key 也使用默认的 TTL
+	BatchWindow time.Duration
+	// BatchMaxKeys 是一次 BatchGetter 调用的最大 key 数量，默认为 defaultBatchMaxKeys
+	BatchMaxKeys int
+
+	// LoadTimeout 是一次未命中的加载（访问远程节点和数据源）的超时时间，默认为 defaultLoadTimeout
+	// 并发的调用方共享同一次加载，加载不受某个调用方的 ctx 影响，所有调用方都放弃之后才会被取消
+	LoadTimeout time.Duration
+	// PeerTimeout 是加载时每次访问远程节点的超时时间，默认为 defaultFetchTimeout
+	// 一个节点超时后会继续尝试下一个副本节点或者回源，不会用完整个 LoadTimeout
+	PeerTimeout time.Duration
+}
+
+const (
+	// defaultHotCacheRate 是默认的 hotCache 采样概率
+	defaultHotCacheRate = 0.1
+	// defaultNegativeMaxEntries 是默认缓存不存在的 key 的最大数量
+	defaultNegativeMaxEntries = 10000
+	// defaultLoadTimeout 是默认的加载超时时间
+	defaultLoadTimeout = 30 * time.Second
+)
+
+// CacheType 表示 Group 中的缓存类型
+type CacheType int
+
+const (
+	MainCache CacheType = iota + 1 // MainCache 保存当前节点负责的 key
+	HotCache                       // HotCache 保存远程节点负责的热点 key
+)
+
 var (
 	mu sync.RWMutex
 	// groups 管理当前所有的 Group，是并发安全的
@@ -39,17 +151,103 @@ func NewGroup(name string, maxEntries int, getter Getter) *Group {
 	if getter == nil {
 		panic("nil getter")
 	}
+	g, err := NewGroupWithOptions(name, getter, GroupOptions{MaxEntries: maxEntries})
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// NewGroupWithOptions 根据 opts 创建一个 Group 实例，并注册到 groups 中
+func NewGroupWithOptions(name string, getter Getter, opts GroupOptions) (*Group, error) {
+	if getter == nil {
+		return nil, fmt.Errorf("nil getter")
+	}
+	if opts.TTL < 0 {
+		return nil, fmt.Errorf("invalid ttl %v", opts.TTL)
+	}
+	if opts.MaxEntries < 0 || opts.MaxBytes < 0 {
+		return nil, fmt.Errorf("invalid cache size %d entries, %d bytes", opts.MaxEntries, opts.MaxBytes)
+	}
+	if opts.Policy == "" {
+		opts.Policy = purgekit.PolicyLRU
+	}
+	if opts.HotCacheMaxEntries < 0 || opts.HotCacheMaxBytes < 0 {
+		return nil, fmt.Errorf("invalid hot cache size %d entries, %d bytes", opts.HotCacheMaxEntries, opts.HotCacheMaxBytes)
+	}
+	if opts.HotCacheRate < 0 || opts.HotCacheRate > 1 {
+		return nil, fmt.Errorf("invalid hot cache rate %v", opts.HotCacheRate)
+	}
+	if opts.HotCacheRate == 0 {
+		opts.HotCacheRate = defaultHotCacheRate
+	}
+	if opts.NegativeTTL < 0 || opts.NegativeMaxEntries < 0 {
+		return nil, fmt.Errorf("invalid negative cache ttl %v, %d entries", opts.NegativeTTL, opts.NegativeMaxEntries)
+	}
+	if opts.NegativeMaxEntries == 0 {
+		opts.NegativeMaxEntries = defaultNegativeMaxEntries
+	}
+	if opts.Replication < 0 {
+		return nil, fmt.Errorf("invalid replication %d", opts.Replication)
+	}
+	if opts.BatchWindow < 0 || opts.BatchMaxKeys < 0 {
+		return nil, fmt.Errorf("invalid batch window %v, %d keys", opts.BatchWindow, opts.BatchMaxKeys)
+	}
+	if opts.BatchMaxKeys == 0 {
+		opts.BatchMaxKeys = defaultBatchMaxKeys
+	}
+	if opts.LoadTimeout < 0 {
+		return nil, fmt.Errorf("invalid load timeout %v", opts.LoadTimeout)
+	}
+	if opts.LoadTimeout == 0 {
+		opts.LoadTimeout = defaultLoadTimeout
+	}
+	if opts.PeerTimeout < 0 {
+		return nil, fmt.Errorf("invalid peer timeout %v", opts.PeerTimeout)
+	}
+	if opts.PeerTimeout == 0 {
+		opts.PeerTimeout = defaultFetchTimeout
+	}
+	mainCache, err := newCache(opts.Policy, opts.MaxEntries, opts.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
 	g := &Group{
-		name:   name,
-		getter: getter,
-		// mainCache 的初始化未完成
-		mainCache: newCache(maxEntries),
-		flight:    &singleflight.Flight{},
+		name:        name,
+		getter:      getter,
+		mainCache:   mainCache,
+		hotRate:     opts.HotCacheRate,
+		missTTL:     opts.NegativeTTL,
+		flight:      &singleflight.Flight{},
+		ttl:         opts.TTL,
+		replicas:    opts.Replication,
+		timeout:     opts.LoadTimeout,
+		peerTimeout: opts.PeerTimeout,
+	}
+	if opts.HotCacheMaxEntries > 0 || opts.HotCacheMaxBytes > 0 {
+		g.hotCache, err = newCache(purgekit.PolicyLRU, opts.HotCacheMaxEntries, opts.HotCacheMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if bg, ok := getter.(BatchGetter); ok && opts.BatchWindow > 0 {
+		g.batcher = newBatcher(bg, opts.BatchWindow, opts.BatchMaxKeys, &g.stats)
+	}
+	if opts.NegativeTTL > 0 {
+		g.missCache, err = newCache(purgekit.PolicyLRU, opts.NegativeMaxEntries, 0)
+		if err != nil {
+			return nil, err
+		}
 	}
 	mu.Lock()
+	old := groups[name]
 	groups[name] = g
 	mu.Unlock()
-	return g
+	// 同名的旧 Group 被替换后不会再被访问，释放它的缓存，避免占用 totalBytes 和清理协程
+	if old != nil {
+		old.close()
+	}
+	return g, nil
 }
 
 // RegisterPicker 将节点选择器注册到 Group
@@ -68,69 +266,350 @@ func GetGroup(name string) *Group {
 	return g
 }
 
-// DestroyGroup 将 name 对饮的 Group 下线
+// DestroyGroup 将 name 对应的 Group 下线并释放它的缓存
+// Picker 可能被多个 Group 共享，不会被停止，server 需要由创建者调用 Stop 停止
 func DestroyGroup(name string) {
 	g := GetGroup(name)
 	if g != nil {
-		picker := g.server.(*server)
-		picker.Stop()
 		mu.Lock()
 		delete(groups, name)
 		mu.Unlock()
-		log.Printf("Destroy cache %s %s", name, picker.addr)
+		g.close()
+		log.Printf("Destroy cache %s", name)
+	}
+}
+
+// close 释放 Group 的所有缓存
+func (g *Group) close() {
+	g.mainCache.close()
+	if g.hotCache != nil {
+		g.hotCache.close()
+	}
+	if g.missCache != nil {
+		g.missCache.close()
 	}
 }
 
 // Get 尝试从当前节点获取 key 对应的值
 // 如果本地不存在，尝试从其他节点获得
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 与 Get 相同，但 ctx 的超时和取消会传递到远程节点和数据源
+func (g *Group) GetContext(ctx context.Context, key string) (ByteView, error) {
+	g.stats.gets.Add(1)
 	if key == "" {
-		return ByteView{}, fmt.Errorf("key is required")
+		return ByteView{}, ErrKeyRequired
 	}
-	if v, ok := g.mainCache.get(key); ok {
+	if v, ok := g.lookupCache(key); ok {
 		log.Println("Pcache hit")
+		g.stats.hits.Add(1)
 		return v, nil
 	}
-	return g.load(key)
+	if g.missCache != nil {
+		if _, ok := g.missCache.get(key); ok {
+			g.stats.negativeHits.Add(1)
+			return ByteView{}, ErrNotFound
+		}
+	}
+	g.stats.misses.Add(1)
+	return g.load(ctx, key)
+}
+
+// lookupCache 依次从 mainCache 和 hotCache 中查找 key
+func (g *Group) lookupCache(key string) (ByteView, bool) {
+	if v, ok := g.mainCache.get(key); ok {
+		return v, true
+	}
+	if g.hotCache != nil {
+		return g.hotCache.get(key)
+	}
+	return ByteView{}, false
 }
 
 // load 使用 flight 保证同一个 key 不会多次请求
 // 如果远程节点当前也没有缓存，会调用 getter 从数据源获取
-func (g *Group) load(key string) (value ByteView, err error) {
-	view, err := g.flight.Fly(key, func() (interface{}, error) {
-		if g.server != nil {
-			if fetcher, ok := g.server.Pick(key); ok {
-				bytes, err := fetcher.Fetch(g.name, key)
-				if err == nil {
-					return ByteView{b: cloneBytes(bytes)}, nil
-				}
-				log.Printf("failed to get %s from peer, %s\n", key, err.Error())
+// 加载使用独立的 ctx 和 Group 的超时时间，每个调用方只能通过自己的 ctx 放弃等待
+func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
+	if err := ctx.Err(); err != nil {
+		return ByteView{}, err
+	}
+	var executed atomic.Bool
+	view, err, _ := g.flight.FlyDetached(ctx, key, func(ctx context.Context) (interface{}, error) {
+		executed.Store(true)
+		ctx, cancel := context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+		ver := g.versions.get(key)
+		for _, fetcher := range g.pickPeers(ctx, key) {
+			value, err := g.fetch(ctx, fetcher, key)
+			if r, done := g.peerResult(ctx, key, ver, value, err); done {
+				return r.Value, r.Err
 			}
 		}
-		return g.getLocally(key)
+		return g.getLocally(ctx, key, ver)
 	})
+	// 放弃等待的调用方不计入合并的次数
+	if !executed.Load() && ctx.Err() == nil {
+		g.stats.dedups.Add(1)
+	}
+	if err != nil {
+		return ByteView{}, err
+	}
+	return view.(ByteView), nil
+}
+
+// fetch 从 fetcher 获取 key，最多等待 peerTimeout 的时间，ctx 剩余的时间更短时以 ctx 为准
+func (g *Group) fetch(ctx context.Context, fetcher Fetcher, key string) (ByteView, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.peerTimeout)
+	defer cancel()
+	return fetcher.Fetch(ctx, g.name, key)
+}
+
+// peerResult 处理从远程节点获取 key 的结果，done 为 false 时应当尝试其他来源
+// ver 是开始获取时 key 的版本，key 在获取期间被修改过时结果不会写入缓存
+func (g *Group) peerResult(ctx context.Context, key string, ver uint64, value ByteView, err error) (r Result, done bool) {
 	if err == nil {
-		return view.(ByteView), err
+		g.stats.peerLoads.Add(1)
+		// 只保存一部分远程的值，避免 hotCache 被偶尔访问的 key 占满
+		if g.hotCache != nil && rand.Float64() < g.hotRate {
+			value.d = new(decoded)
+			g.versions.apply(key, ver, func() { g.hotCache.add(key, value) })
+		}
+		return Result{Value: value}, true
+	}
+	g.stats.peerErrors.Add(1)
+	// 调用方已经放弃或者加载已经超时，不必再回源
+	// 单个节点超时时 ctx 没有结束，继续尝试其他来源
+	if ctx.Err() != nil {
+		return Result{Err: ctx.Err()}, true
+	}
+	// 远程节点已经确认数据源中不存在，不必再回源
+	if errors.Is(err, ErrNotFound) {
+		g.versions.apply(key, ver, func() { g.populateMiss(key) })
+		return Result{Err: err}, true
+	}
+	log.Printf("failed to get %s from peer, %s\n", key, err.Error())
+	return Result{}, false
+}
+
+// pickPeers 返回读取 key 时依次尝试的远程节点，为空表示由当前节点回源
+// 其他节点转发来的请求总是由当前节点回源
+func (g *Group) pickPeers(ctx context.Context, key string) []Fetcher {
+	if g.server == nil || isPeerRequest(ctx) {
+		return nil
+	}
+	if g.replicas > 1 {
+		if rp, ok := g.server.(ReplicaPicker); ok {
+			return rp.PickReplicas(key, g.replicas)
+		}
+	}
+	if fetcher, ok := g.server.Pick(key); ok {
+		return []Fetcher{fetcher}
 	}
-	return ByteView{}, nil
+	return nil
 }
 
-// getLocally 从数据源获取数据
-func (g *Group) getLocally(key string) (ByteView, error) {
-	bytes, err := g.getter.Get(key)
+// getLocally 从数据源获取数据，ver 是开始加载时 key 的版本
+func (g *Group) getLocally(ctx context.Context, key string, ver uint64) (ByteView, error) {
+	var (
+		bytes  []byte
+		expire time.Time
+		err    error
+	)
+	if g.batcher != nil {
+		// 与其他并发的未命中合并为一次 GetBatch 调用
+		bytes, err = g.batcher.get(ctx, key)
+		return g.storeLoaded(key, ver, bytes, expire, err)
+	}
+	switch getter := g.getter.(type) {
+	case ExpireGetter:
+		bytes, expire, err = getter.GetWithExpire(ctx, key)
+	case ContextGetter:
+		bytes, err = getter.GetContext(ctx, key)
+	default:
+		bytes, err = getter.Get(key)
+	}
+	return g.storeLoaded(key, ver, bytes, expire, err)
+}
+
+// storeLoaded 记录从数据源获取 key 的结果，成功时写入 mainCache，不存在时写入 missCache
+// key 的版本不再是 ver 时说明加载期间 key 被 Set 或 Remove 修改过，结果不会写入缓存
+func (g *Group) storeLoaded(key string, ver uint64, bytes []byte, expire time.Time, err error) (ByteView, error) {
 	if err != nil {
+		g.stats.localLoadErrs.Add(1)
+		if errors.Is(err, ErrNotFound) {
+			g.versions.apply(key, ver, func() { g.populateMiss(key) })
+		}
 		return ByteView{}, err
 	}
-	value := ByteView{b: cloneBytes(bytes)}
-	g.populate(key, value)
+	g.stats.localLoads.Add(1)
+	if expire.IsZero() && g.ttl > 0 {
+		expire = time.Now().Add(g.ttl)
+	}
+	value := ByteView{b: cloneBytes(bytes), e: expire, d: new(decoded)}
+	g.versions.apply(key, ver, func() { g.populate(key, value) })
 	return value, nil
 }
 
+// Set 将 key 的值设置为 value，并写入 key 所属的节点
+// expire 为零值时使用所属节点的默认 TTL
+// 所属节点是远程节点时，它的 Fetcher 需要实现 Mutator，否则返回 ErrNotSupported
+// 开启副本时同时删除其他副本节点中的旧值，避免所属节点不可用时读到旧值
+// 正在进行的对 key 的加载的结果不会再写入缓存，之后的 Get 会重新加载
+func (g *Group) Set(ctx context.Context, key string, value []byte, expire time.Time) error {
+	if key == "" {
+		return ErrKeyRequired
+	}
+	var owner Fetcher
+	if g.server != nil {
+		owner, _ = g.server.Pick(key)
+	}
+	if owner != nil {
+		m, ok := owner.(Mutator)
+		if !ok {
+			return ErrNotSupported
+		}
+		if err := m.Set(ctx, g.name, key, value, expire); err != nil {
+			return err
+		}
+		// 当前节点可能保存着旧值
+		g.removeLocally(key)
+	} else {
+		g.setLocally(key, value, expire)
+	}
+	var others []Fetcher
+	for _, fetcher := range g.replicaTargets(key) {
+		if fetcher != owner {
+			others = append(others, fetcher)
+		}
+	}
+	return g.removeFrom(ctx, others, key)
+}
+
+// Remove 从 key 所属的节点、副本节点和当前节点删除 key 的缓存
+// 开启副本并且 Picker 实现了 ReplicaLister 时删除所有副本节点中的缓存，
+// 远程节点的 Fetcher 需要实现 Mutator，否则返回 ErrNotSupported
+// 返回遇到的第一个错误
+func (g *Group) Remove(ctx context.Context, key string) error {
+	if key == "" {
+		return ErrKeyRequired
+	}
+	g.removeLocally(key)
+	return g.removeFrom(ctx, g.replicaTargets(key), key)
+}
+
+// replicaTargets 返回可能缓存着 key 的远程节点，用于修改 key
+// 与读取时使用的 pickPeers 不同，包括排在当前节点之后的副本节点
+func (g *Group) replicaTargets(key string) []Fetcher {
+	if g.server == nil {
+		return nil
+	}
+	if g.replicas > 1 {
+		if rl, ok := g.server.(ReplicaLister); ok {
+			return rl.Replicas(key, g.replicas)
+		}
+	}
+	if fetcher, ok := g.server.Pick(key); ok {
+		return []Fetcher{fetcher}
+	}
+	return nil
+}
+
+// Invalidate 从所有节点删除 key 的缓存，用于数据源更新之后保证缓存一致
+// 如果 Picker 没有实现 PeerLister，与 Remove 相同，没有实现 Mutator 的节点返回 ErrNotSupported
+// 返回遇到的第一个错误
+func (g *Group) Invalidate(ctx context.Context, key string) error {
+	lister, ok := g.server.(PeerLister)
+	if !ok {
+		return g.Remove(ctx, key)
+	}
+	if key == "" {
+		return ErrKeyRequired
+	}
+	g.removeLocally(key)
+	return g.removeFrom(ctx, lister.Fetchers(), key)
+}
+
+// removeFrom 并发地从 fetchers 删除 key 的缓存，返回遇到的第一个错误
+func (g *Group) removeFrom(ctx context.Context, fetchers []Fetcher, key string) error {
+	errs := make([]error, len(fetchers))
+	var wg sync.WaitGroup
+	for i, fetcher := range fetchers {
+		m, ok := fetcher.(Mutator)
+		if !ok {
+			errs[i] = ErrNotSupported
+			continue
+		}
+		wg.Add(1)
+		go func(i int, m Mutator) {
+			defer wg.Done()
+			errs[i] = m.Remove(ctx, g.name, key)
+		}(i, m)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setLocally 将值写入当前节点的缓存，并丢弃正在进行的对 key 的加载
+func (g *Group) setLocally(key string, value []byte, expire time.Time) {
+	if expire.IsZero() && g.ttl > 0 {
+		expire = time.Now().Add(g.ttl)
+	}
+	view := ByteView{b: cloneBytes(value), e: expire, d: new(decoded)}
+	g.flight.Forget(key)
+	g.versions.update(key, func() { g.populate(key, view) })
+}
+
+// removeLocally 删除当前节点中 key 的缓存，并丢弃正在进行的对 key 的加载
+func (g *Group) removeLocally(key string) {
+	g.flight.Forget(key)
+	g.versions.update(key, func() {
+		g.mainCache.remove(key)
+		if g.hotCache != nil {
+			g.hotCache.remove(key)
+		}
+		if g.missCache != nil {
+			g.missCache.remove(key)
+		}
+	})
+}
+
 // populate 像缓存中添加数据
 func (g *Group) populate(key string, value ByteView) {
+	if g.missCache != nil {
+		g.missCache.remove(key)
+	}
 	g.mainCache.add(key, value)
 }
 
+// populateMiss 记录数据源中不存在的 key，在 missTTL 之后过期
+func (g *Group) populateMiss(key string) {
+	if g.missCache != nil {
+		g.missCache.add(key, ByteView{e: time.Now().Add(g.missTTL)})
+	}
+}
+
+// CacheStats 返回 which 对应缓存的统计信息
+func (g *Group) CacheStats(which CacheType) CacheStats {
+	switch which {
+	case MainCache:
+		return g.mainCache.stats()
+	case HotCache:
+		if g.hotCache != nil {
+			return g.hotCache.stats()
+		}
+		return CacheStats{}
+	default:
+		return CacheStats{}
+	}
+}
+
 // Name 返回当前 Group 的名字
 func (g *Group) Name() string {
 	return g.name
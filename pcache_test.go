@@ -0,0 +1,623 @@
+package pcache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+func TestGetContext(t *testing.T) {
+	g := NewGroup("ctx-test", 0, ContextGetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if v, _ := ctx.Value(ctxKey{}).(string); v != "tom" {
+			t.Fatalf("getter should receive caller's context, but got value %q", v)
+		}
+		return []byte("630"), nil
+	}))
+	ctx := context.WithValue(context.Background(), ctxKey{}, "tom")
+	view, err := g.GetContext(ctx, "Tom")
+	if err != nil || view.String() != "630" {
+		t.Fatalf("want 630, but got %q, %v", view.String(), err)
+	}
+}
+
+func TestGetContextCanceled(t *testing.T) {
+	g := NewGroup("ctx-cancel-test", 0, GetterFunc(func(key string) ([]byte, error) {
+		t.Fatal("getter should not be called with canceled context")
+		return nil, nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.GetContext(ctx, "Tom"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, but got %v", err)
+	}
+}
+
+// TestGetContextLeaderCanceled 检查发起加载的调用方放弃之后，等待同一个 key 的调用方仍然能够得到结果
+func TestGetContextLeaderCanceled(t *testing.T) {
+	var once sync.Once
+	start, release := make(chan struct{}), make(chan struct{})
+	g := NewGroup("ctx-leader-test", 0, ContextGetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		once.Do(func() { close(start) })
+		select {
+		case <-release:
+			return []byte("630"), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := g.GetContext(ctx, "Tom")
+		leader <- err
+	}()
+	<-start
+	result := make(chan error)
+	go func() {
+		view, err := g.GetContext(context.Background(), "Tom")
+		if err == nil && view.String() != "630" {
+			err = errors.New("want 630, but got " + view.String())
+		}
+		result <- err
+	}()
+	// 等待第二个调用方加入，没有加入时它会重新加载，测试同样能通过
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, but got %v", err)
+	}
+	close(release)
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	loads := 0
+	g, err := NewGroupWithOptions("ttl-test", GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	}), GroupOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err := g.Get("Tom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Expire().IsZero() {
+		t.Fatal("value should expire with default ttl")
+	}
+	g.Get("Tom")
+	if loads != 1 {
+		t.Fatalf("value should be cached before expiration, but loaded %v times", loads)
+	}
+}
+
+func TestGetterExpire(t *testing.T) {
+	loads := 0
+	g := NewGroup("expire-getter-test", 0, ExpireGetterFunc(func(ctx context.Context, key string) ([]byte, time.Time, error) {
+		loads++
+		return []byte(key), time.Now().Add(-time.Second), nil
+	}))
+	g.Get("Tom")
+	g.Get("Tom")
+	if loads != 2 {
+		t.Fatalf("expired value should be reloaded, but loaded %v times", loads)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("value"), nil
+	})
+	g, err := NewGroupWithOptions("bytes-test", getter, GroupOptions{MaxBytes: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"k1", "k2", "k3"} {
+		g.Get(key)
+	}
+	if n := g.mainCache.bytes(); n != 14 {
+		t.Fatalf("cache should be kept under 20 bytes, but got %v", n)
+	}
+}
+
+func TestProcessMaxBytes(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("value"), nil
+	})
+	g1 := NewGroup("process-bytes-test1", 0, getter)
+	g2 := NewGroup("process-bytes-test2", 0, getter)
+	defer g1.mainCache.close()
+	defer g2.mainCache.close()
+	base := totalBytes.Load()
+	SetMaxBytes(base + 30)
+	defer SetMaxBytes(0)
+	for _, key := range []string{"k1", "k2", "k3"} {
+		g1.Get(key)
+	}
+	for _, key := range []string{"k1", "k2", "k3"} {
+		g2.Get(key)
+	}
+	if n := totalBytes.Load() - base; n > 30 {
+		t.Fatalf("process should be kept under 30 bytes, but got %v", n)
+	}
+	if n := g1.mainCache.bytes() + g2.mainCache.bytes(); n != 28 {
+		t.Fatalf("groups should hold 28 bytes, but got %v", n)
+	}
+}
+
+func TestProcessMaxBytesKeepNewEntry(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("value"), nil
+	})
+	g, err := NewGroupWithOptions("process-bytes-lfu-test", getter, GroupOptions{Policy: "lfu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.mainCache.close()
+	base := totalBytes.Load()
+	SetMaxBytes(base + 20)
+	defer SetMaxBytes(0)
+	for _, key := range []string{"k1", "k2", "k1", "k2"} {
+		g.Get(key)
+	}
+	// k3 使用次数最少，是 LFU 首先淘汰的条目，但它是刚写入的，应该淘汰其他条目
+	g.Get("k3")
+	if _, ok := g.mainCache.get("k3"); !ok {
+		t.Fatalf("the entry just added should not be evicted")
+	}
+	if n := totalBytes.Load() - base; n > 20 {
+		t.Fatalf("process should be kept under 20 bytes, but got %v", n)
+	}
+}
+
+func TestReplaceGroup(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("value"), nil
+	})
+	base := totalBytes.Load()
+	old := NewGroup("replace-test", 0, getter)
+	old.Get("k1")
+	if n := totalBytes.Load() - base; n != 7 {
+		t.Fatalf("old group should hold 7 bytes, but got %v", n)
+	}
+	g := NewGroup("replace-test", 0, getter)
+	defer DestroyGroup("replace-test")
+	if GetGroup("replace-test") != g {
+		t.Fatal("the new group should replace the old one")
+	}
+	if n := totalBytes.Load() - base; n != 0 {
+		t.Fatalf("the replaced group should release its bytes, but %v bytes kept", n)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	for _, policy := range []string{"lru", "lfu", "arc"} {
+		g, err := NewGroupWithOptions("policy-test-"+policy, getter, GroupOptions{Policy: policy, MaxEntries: 2})
+		if err != nil {
+			t.Fatalf("%s: %v", policy, err)
+		}
+		for _, key := range []string{"k1", "k2", "k3", "k1"} {
+			if view, err := g.Get(key); err != nil || view.String() != key {
+				t.Fatalf("%s: want %s, but got %q, %v", policy, key, view.String(), err)
+			}
+		}
+	}
+	if _, err := NewGroupWithOptions("policy-test-unknown", getter, GroupOptions{Policy: "fifo"}); err == nil {
+		t.Fatal("unknown policy should return an error")
+	}
+}
+
+// fakePeer 将请求直接转发给另一个 Group，模拟远程节点
+type fakePeer struct {
+	group *Group
+}
+
+func (p *fakePeer) Fetch(ctx context.Context, group string, key string) (ByteView, error) {
+	return p.group.GetContext(ctx, key)
+}
+
+func (p *fakePeer) Set(ctx context.Context, group string, key string, value []byte, expire time.Time) error {
+	p.group.setLocally(key, value, expire)
+	return nil
+}
+
+func (p *fakePeer) Remove(ctx context.Context, group string, key string) error {
+	p.group.removeLocally(key)
+	return nil
+}
+
+// fakePicker 将 remote 开头的 key 交给 owner 处理
+type fakePicker struct {
+	owner *fakePeer
+	peers []Fetcher
+}
+
+func (p *fakePicker) Pick(key string) (Fetcher, bool) {
+	if strings.HasPrefix(key, "remote") {
+		return p.owner, true
+	}
+	return nil, false
+}
+
+func (p *fakePicker) Fetchers() []Fetcher {
+	return p.peers
+}
+
+func TestSetRemove(t *testing.T) {
+	source := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("source"), nil
+	})
+	owner := &fakePeer{NewGroup("set-test-owner", 0, source)}
+	g := NewGroup("set-test", 0, source)
+	g.RegisterPicker(&fakePicker{owner: owner})
+	ctx := context.Background()
+
+	for _, key := range []string{"local", "remote"} {
+		if err := g.Set(ctx, key, []byte("fresh"), time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		if view, _ := g.Get(key); view.String() != "fresh" {
+			t.Fatalf("%s: want fresh, but got %s", key, view.String())
+		}
+		if err := g.Remove(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+		if view, _ := g.Get(key); view.String() != "source" {
+			t.Fatalf("%s: want source after remove, but got %s", key, view.String())
+		}
+	}
+	if _, ok := g.mainCache.get("remote"); ok {
+		t.Fatal("value owned by remote peer should not be cached locally")
+	}
+}
+
+// fetchOnlyPeer 是没有实现 Mutator 的远程节点
+type fetchOnlyPeer struct{}
+
+func (fetchOnlyPeer) Fetch(ctx context.Context, group string, key string) (ByteView, error) {
+	return ByteView{}, ErrNotFound
+}
+
+func TestSetNotSupported(t *testing.T) {
+	g := NewGroup("set-unsupported-test", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("source"), nil
+	}))
+	g.RegisterPicker(&replicaPicker{replicas: []Fetcher{fetchOnlyPeer{}}})
+	if err := g.Set(context.Background(), "Tom", []byte("fresh"), time.Time{}); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("want ErrNotSupported, but got %v", err)
+	}
+	if err := g.Remove(context.Background(), "Tom"); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("want ErrNotSupported, but got %v", err)
+	}
+}
+
+// TestSetDuringLoad 检查 Set 之前开始的加载的结果不会覆盖 Set 写入的值
+func TestSetDuringLoad(t *testing.T) {
+	start, release := make(chan struct{}), make(chan struct{})
+	loads := 0
+	g := NewGroup("set-during-load-test", 0, GetterFunc(func(key string) ([]byte, error) {
+		if loads++; loads == 1 {
+			close(start)
+			<-release
+			return []byte("stale"), nil
+		}
+		return []byte("source"), nil
+	}))
+	loaded := make(chan string)
+	go func() {
+		view, _ := g.Get("Tom")
+		loaded <- view.String()
+	}()
+	<-start
+	if err := g.Set(context.Background(), "Tom", []byte("fresh"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if v := <-loaded; v != "stale" {
+		t.Fatalf("the load started before Set should return its own result, but got %s", v)
+	}
+	if view, _ := g.Get("Tom"); view.String() != "fresh" {
+		t.Fatalf("stale load should not overwrite the value set, but got %s", view.String())
+	}
+
+	// Remove 之后开始的 Get 不会等待之前的加载
+	start, release = make(chan struct{}), make(chan struct{})
+	loads = 0
+	g.Remove(context.Background(), "Tom")
+	go g.Get("Tom")
+	<-start
+	g.Remove(context.Background(), "Tom")
+	if view, _ := g.Get("Tom"); view.String() != "source" {
+		t.Fatalf("Get after Remove should load again, but got %s", view.String())
+	}
+	close(release)
+}
+
+func TestInvalidate(t *testing.T) {
+	source := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("source"), nil
+	})
+	owner := &fakePeer{NewGroup("invalidate-test-owner", 0, source)}
+	other := &fakePeer{NewGroup("invalidate-test-other", 0, source)}
+	g := NewGroup("invalidate-test", 0, source)
+	g.RegisterPicker(&fakePicker{owner: owner, peers: []Fetcher{owner, other}})
+
+	other.group.setLocally("remote", []byte("stale"), time.Time{})
+	owner.group.setLocally("remote", []byte("stale"), time.Time{})
+	if err := g.Invalidate(context.Background(), "remote"); err != nil {
+		t.Fatal(err)
+	}
+	for _, peer := range []*fakePeer{owner, other} {
+		if _, ok := peer.group.mainCache.get("remote"); ok {
+			t.Fatalf("%s: key should be invalidated", peer.group.Name())
+		}
+	}
+}
+
+func TestHotCache(t *testing.T) {
+	source := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("source"), nil
+	})
+	owner := &fakePeer{NewGroup("hot-test-owner", 0, source)}
+	g, err := NewGroupWithOptions("hot-test", source, GroupOptions{HotCacheMaxEntries: 1, HotCacheRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.RegisterPicker(&fakePicker{owner: owner})
+	for i := 0; i < 3; i++ {
+		if view, err := g.Get("remote"); err != nil || view.String() != "source" {
+			t.Fatalf("want source, but got %q, %v", view.String(), err)
+		}
+	}
+	if gets := owner.group.CacheStats(MainCache).Gets; gets != 1 {
+		t.Fatalf("hot key should be fetched from owner once, but got %v", gets)
+	}
+	g.Get("remote2")
+	g.Get("remote2")
+	stats := g.CacheStats(HotCache)
+	if stats.Items != 1 || stats.Evictions != 1 || stats.Hits != 3 {
+		t.Fatalf("unexpected hot cache stats %+v", stats)
+	}
+	if stats := g.CacheStats(MainCache); stats.Items != 0 {
+		t.Fatalf("remote keys should not be stored in main cache, but got %+v", stats)
+	}
+}
+
+func TestStats(t *testing.T) {
+	source := GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, errors.New("not found")
+		}
+		return []byte("source"), nil
+	})
+	owner := &fakePeer{NewGroup("stats-test-owner", 0, source)}
+	g := NewGroup("stats-test", 0, source)
+	g.RegisterPicker(&fakePicker{owner: owner})
+	g.Get("Tom")
+	g.Get("Tom")
+	g.Get("missing")
+	g.Get("remote")
+	stats := g.Stats()
+	want := GroupStats{Gets: 4, Hits: 1, Misses: 3, PeerLoads: 1, LocalLoads: 1, LocalLoadErrs: 1}
+	want.MainCache = stats.MainCache
+	if stats != want {
+		t.Fatalf("got stats %+v, want %+v", stats, want)
+	}
+	if stats.MainCache.Items != 1 || stats.MainCache.Bytes != int64(len("Tom")+len("source")) {
+		t.Fatalf("unexpected main cache stats %+v", stats.MainCache)
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	loads := 0
+	source := GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return nil, ErrNotFound
+	})
+	owner := &fakePeer{NewGroup("negative-test-owner", 0, source)}
+	g, err := NewGroupWithOptions("negative-test", source, GroupOptions{NegativeTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.RegisterPicker(&fakePicker{owner: owner})
+	for _, key := range []string{"local", "local", "remote", "remote"} {
+		if _, err := g.Get(key); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: want ErrNotFound, but got %v", key, err)
+		}
+	}
+	if loads != 2 {
+		t.Fatalf("missing keys should be loaded once each, but loaded %v times", loads)
+	}
+	if n := g.Stats().NegativeHits; n != 2 {
+		t.Fatalf("want 2 negative hits, but got %v", n)
+	}
+	if err := g.Set(context.Background(), "local", []byte("fresh"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if view, err := g.Get("local"); err != nil || view.String() != "fresh" {
+		t.Fatalf("set should clear the negative entry, but got %q, %v", view.String(), err)
+	}
+}
+
+// downPeer 模拟不可用的远程节点
+type downPeer struct{}
+
+func (downPeer) Fetch(ctx context.Context, group string, key string) (ByteView, error) {
+	return ByteView{}, ErrPeerUnavailable
+}
+
+func (downPeer) Set(ctx context.Context, group string, key string, value []byte, expire time.Time) error {
+	return ErrPeerUnavailable
+}
+
+func (downPeer) Remove(ctx context.Context, group string, key string) error {
+	return ErrPeerUnavailable
+}
+
+// replicaPicker 将所有 key 交给 replicas 依次处理
+type replicaPicker struct {
+	replicas []Fetcher
+}
+
+func (p *replicaPicker) Pick(key string) (Fetcher, bool) {
+	return p.replicas[0], true
+}
+
+func (p *replicaPicker) PickReplicas(key string, n int) []Fetcher {
+	if n > len(p.replicas) {
+		n = len(p.replicas)
+	}
+	return p.replicas[:n]
+}
+
+func (p *replicaPicker) Replicas(key string, n int) []Fetcher {
+	return p.PickReplicas(key, n)
+}
+
+func TestReplication(t *testing.T) {
+	var loads int
+	source := GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("source"), nil
+	})
+	if _, err := NewGroupWithOptions("replication-test-invalid", source, GroupOptions{Replication: -1}); err == nil {
+		t.Fatal("negative replication should return an error")
+	}
+	replica := &fakePeer{NewGroup("replication-test-replica", 0, source)}
+	g, err := NewGroupWithOptions("replication-test", source, GroupOptions{Replication: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.RegisterPicker(&replicaPicker{replicas: []Fetcher{downPeer{}, replica}})
+
+	if view, err := g.Get("Tom"); err != nil || view.String() != "source" {
+		t.Fatalf("want source, but got %q, %v", view.String(), err)
+	}
+	if _, ok := replica.group.mainCache.get("Tom"); !ok {
+		t.Fatal("replica should load the key when the owner is down")
+	}
+	if _, ok := g.mainCache.get("Tom"); ok {
+		t.Fatal("key loaded from replica should not be cached in main cache")
+	}
+	if stats := g.Stats(); stats.PeerErrors != 1 || stats.PeerLoads != 1 || stats.LocalLoads != 0 {
+		t.Fatalf("want 1 peer error and 1 peer load, but got %+v", stats)
+	}
+	if loads != 1 {
+		t.Fatalf("only the replica should load from source, but loaded %v times", loads)
+	}
+
+	// Remove 删除所有副本中的缓存，返回不可用节点的错误
+	if err := g.Remove(context.Background(), "Tom"); !errors.Is(err, ErrPeerUnavailable) {
+		t.Fatalf("want ErrPeerUnavailable from the owner, but got %v", err)
+	}
+	if _, ok := replica.group.mainCache.get("Tom"); ok {
+		t.Fatal("remove should delete the key from replica")
+	}
+
+	// Set 写入所属节点，并删除其他副本中的旧值
+	owner := &fakePeer{NewGroup("replication-test-owner", 0, source)}
+	replica.group.Get("Jack")
+	g3, _ := NewGroupWithOptions("replication-test-set", source, GroupOptions{Replication: 2})
+	g3.RegisterPicker(&replicaPicker{replicas: []Fetcher{owner, replica}})
+	if err := g3.Set(context.Background(), "Jack", []byte("new"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if view, ok := owner.group.mainCache.get("Jack"); !ok || view.String() != "new" {
+		t.Fatalf("owner should hold the new value, but got %q", view.String())
+	}
+	if _, ok := replica.group.mainCache.get("Jack"); ok {
+		t.Fatal("set should delete the old value from replica")
+	}
+
+	// 没有开启副本时，所属节点失败后直接回源
+	g2 := NewGroup("replication-test-disabled", 0, source)
+	g2.RegisterPicker(&replicaPicker{replicas: []Fetcher{downPeer{}, replica}})
+	g2.Get("Jack")
+	if _, ok := g2.mainCache.get("Jack"); !ok {
+		t.Fatal("key should be loaded locally without replication")
+	}
+	if loads != 3 {
+		t.Fatalf("want 3 loads from source, but got %v", loads)
+	}
+}
+
+// hangPeer 模拟连接正常但一直不返回的远程节点
+type hangPeer struct{}
+
+func (hangPeer) Fetch(ctx context.Context, group string, key string) (ByteView, error) {
+	<-ctx.Done()
+	return ByteView{}, ctx.Err()
+}
+
+// TestPeerTimeout 检查一个节点超时后继续尝试副本节点，而不是用完整个加载的时间
+func TestPeerTimeout(t *testing.T) {
+	source := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("source"), nil
+	})
+	if _, err := NewGroupWithOptions("peer-timeout-test-invalid", source, GroupOptions{PeerTimeout: -1}); err == nil {
+		t.Fatal("negative peer timeout should return an error")
+	}
+	replica := &fakePeer{NewGroup("peer-timeout-test-replica", 0, source)}
+	g, err := NewGroupWithOptions("peer-timeout-test", source, GroupOptions{
+		Replication: 2,
+		PeerTimeout: 50 * time.Millisecond,
+		LoadTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.RegisterPicker(&replicaPicker{replicas: []Fetcher{hangPeer{}, replica}})
+	start := time.Now()
+	if view, err := g.Get("Tom"); err != nil || view.String() != "source" {
+		t.Fatalf("want source from replica, but got %q, %v", view.String(), err)
+	}
+	if _, ok := replica.group.mainCache.get("Tom"); !ok {
+		t.Fatal("replica should serve the key after the owner timed out")
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("owner should time out after the peer timeout, but took %v", d)
+	}
+	if r := g.GetMany(context.Background(), []string{"Jack"}); r[0].Err != nil {
+		t.Fatalf("GetMany should fall back to replica, but got %v", r[0].Err)
+	}
+}
+
+// TestPeerRequest 检查其他节点转发来的请求在本地处理，不会被再次转发
+func TestPeerRequest(t *testing.T) {
+	source := GetterFunc(func(key string) ([]byte, error) {
+		return []byte("source"), nil
+	})
+	g := NewGroup("peer-request-test", 0, source)
+	g.RegisterPicker(&replicaPicker{replicas: []Fetcher{downPeer{}}})
+	ctx := withPeerRequest(context.Background())
+	if view, err := g.GetContext(ctx, "Tom"); err != nil || view.String() != "source" {
+		t.Fatalf("want source, but got %q, %v", view.String(), err)
+	}
+	if r := g.GetMany(ctx, []string{"Jack"}); r[0].Err != nil {
+		t.Fatalf("want Jack loaded locally, but got %v", r[0].Err)
+	}
+	if stats := g.Stats(); stats.PeerErrors != 0 || stats.LocalLoads != 2 {
+		t.Fatalf("forwarded requests should be loaded locally, but got %+v", stats)
+	}
+}
+
+func TestNewByteView(t *testing.T) {
+	b := []byte("630")
+	expire := time.Now().Add(time.Hour)
+	view := NewByteView(b, expire)
+	b[0] = 'x'
+	if view.String() != "630" || !view.Expire().Equal(expire) {
+		t.Fatalf("want a copy of 630 expiring at %v, but got %q, %v", expire, view.String(), view.Expire())
+	}
+}
@@ -23,6 +23,9 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type PcacheClient interface {
 	Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
+	Remove(ctx context.Context, in *Request, opts ...grpc.CallOption) (*RemoveResponse, error)
+	GetMulti(ctx context.Context, in *MultiRequest, opts ...grpc.CallOption) (*MultiResponse, error)
 }
 
 type pcacheClient struct {
@@ -42,11 +45,41 @@ func (c *pcacheClient) Get(ctx context.Context, in *Request, opts ...grpc.CallOp
 	return out, nil
 }
 
+func (c *pcacheClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
+	out := new(SetResponse)
+	err := c.cc.Invoke(ctx, "/pcachepb.Pcache/Set", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pcacheClient) Remove(ctx context.Context, in *Request, opts ...grpc.CallOption) (*RemoveResponse, error) {
+	out := new(RemoveResponse)
+	err := c.cc.Invoke(ctx, "/pcachepb.Pcache/Remove", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pcacheClient) GetMulti(ctx context.Context, in *MultiRequest, opts ...grpc.CallOption) (*MultiResponse, error) {
+	out := new(MultiResponse)
+	err := c.cc.Invoke(ctx, "/pcachepb.Pcache/GetMulti", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PcacheServer is the server API for Pcache service.
 // All implementations must embed UnimplementedPcacheServer
 // for forward compatibility
 type PcacheServer interface {
 	Get(context.Context, *Request) (*Response, error)
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	Remove(context.Context, *Request) (*RemoveResponse, error)
+	GetMulti(context.Context, *MultiRequest) (*MultiResponse, error)
 	mustEmbedUnimplementedPcacheServer()
 }
 
@@ -57,6 +90,15 @@ type UnimplementedPcacheServer struct {
 func (UnimplementedPcacheServer) Get(context.Context, *Request) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
 }
+func (UnimplementedPcacheServer) Set(context.Context, *SetRequest) (*SetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedPcacheServer) Remove(context.Context, *Request) (*RemoveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Remove not implemented")
+}
+func (UnimplementedPcacheServer) GetMulti(context.Context, *MultiRequest) (*MultiResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMulti not implemented")
+}
 func (UnimplementedPcacheServer) mustEmbedUnimplementedPcacheServer() {}
 
 // UnsafePcacheServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +130,60 @@ func _Pcache_Get_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Pcache_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PcacheServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pcachepb.Pcache/Set",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PcacheServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pcache_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PcacheServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pcachepb.Pcache/Remove",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PcacheServer).Remove(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pcache_GetMulti_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PcacheServer).GetMulti(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pcachepb.Pcache/GetMulti",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PcacheServer).GetMulti(ctx, req.(*MultiRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Pcache_ServiceDesc is the grpc.ServiceDesc for Pcache service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,6 +195,18 @@ var Pcache_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Get",
 			Handler:    _Pcache_Get_Handler,
 		},
+		{
+			MethodName: "Set",
+			Handler:    _Pcache_Set_Handler,
+		},
+		{
+			MethodName: "Remove",
+			Handler:    _Pcache_Remove_Handler,
+		},
+		{
+			MethodName: "GetMulti",
+			Handler:    _Pcache_GetMulti_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pcache.proto",
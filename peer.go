@@ -1,11 +1,71 @@
 package pcache
 
+import (
+	"context"
+	"time"
+)
+
 // Picker 定义了节点将请求发送到其他节点的能力
 type Picker interface {
 	Pick(key string) (Fetcher, bool)
 }
 
 // Fetcher 接口定义了向特定客户端请求的能力
+// ctx 的超时和取消会传递给远程调用
 type Fetcher interface {
-	Fetch(group string, key string) ([]byte, error)
+	// Fetch 返回远程节点中 key 的值和过期时间
+	Fetch(ctx context.Context, group string, key string) (ByteView, error)
+}
+
+// Mutator 是 Fetcher 可选实现的接口，用于修改远程节点中的缓存
+// Group.Set、Remove 和 Invalidate 使用它将修改发送到远程节点
+type Mutator interface {
+	// Set 将 key 的值写入远程节点，expire 为零值表示使用远程节点的默认 TTL
+	Set(ctx context.Context, group string, key string, value []byte, expire time.Time) error
+	// Remove 删除远程节点中 key 的缓存
+	Remove(ctx context.Context, group string, key string) error
+}
+
+// MultiFetcher 是 Fetcher 可选实现的接口，一次从远程节点获取多个 key
+// Group.GetMany 使用它让每个远程节点只处理一次请求
+type MultiFetcher interface {
+	// FetchMulti 返回的 values 和 errs 与 keys 一一对应，整个请求失败时返回 err
+	FetchMulti(ctx context.Context, group string, keys []string) (values []ByteView, errs []error, err error)
+}
+
+// ReplicaPicker 是 Picker 可选实现的接口，用于从副本节点读取
+// 所属节点不可用时，Group 会依次尝试副本节点，而不是直接回源
+type ReplicaPicker interface {
+	// PickReplicas 按优先级返回 key 的前 n 个节点中排在当前节点之前的远程节点
+	// 第一个是所属节点，返回空表示当前节点就是所属节点
+	PickReplicas(key string, n int) []Fetcher
+}
+
+// ReplicaLister 是 Picker 可选实现的接口，用于修改副本节点中的缓存
+// 副本节点处理转发来的请求时会缓存 key，Group.Set 和 Remove 使用它删除所有副本中的旧值
+type ReplicaLister interface {
+	// Replicas 返回 key 的前 n 个节点中除当前节点之外的所有远程节点，包括排在当前节点之后的节点
+	Replicas(key string, n int) []Fetcher
+}
+
+// PeerLister 是 Picker 可选实现的接口，返回除当前节点之外的所有远程节点
+// Group.Invalidate 使用它将删除广播到所有节点
+type PeerLister interface {
+	Fetchers() []Fetcher
+}
+
+// peerRequestKey 是 context 中标记请求来自其他节点的 key
+type peerRequestKey struct{}
+
+// withPeerRequest 标记 ctx 对应的请求由其他节点转发而来
+// 这类请求总是由当前节点处理，不会再次选择节点，
+// 避免节点之间的哈希环或负载视图不一致时请求在节点之间来回转发
+func withPeerRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, peerRequestKey{}, true)
+}
+
+// isPeerRequest 判断 ctx 对应的请求是否由其他节点转发而来
+func isPeerRequest(ctx context.Context) bool {
+	forwarded, _ := ctx.Value(peerRequestKey{}).(bool)
+	return forwarded
 }
@@ -1,6 +1,10 @@
 package purgekit
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestArcGet(t *testing.T) {
 	arc := NewARCache(128, nil)
@@ -101,3 +105,94 @@ func TestArcAdative(t *testing.T) {
 		t.Fatalf("arc.p should be 1, but got: %v", arc.p)
 	}
 }
+
+func TestArcExpire(t *testing.T) {
+	arc := NewARCache(4, nil)
+	arc.AddWithExpire(1, 1, time.Now().Add(-time.Second))
+	arc.AddWithExpire(2, 2, time.Now().Add(-time.Second))
+	arc.Add(3, 3)
+	if _, ok := arc.Get(1); ok {
+		t.Fatal("expired entry in t1 should not be returned")
+	}
+	if arc.t2.Len() != 0 || arc.b1.Len() != 0 {
+		t.Fatalf("expired entry should be dropped, but got t2 %v, b1 %v", arc.t2.Len(), arc.b1.Len())
+	}
+	if n := arc.RemoveExpired(); n != 1 || arc.Len() != 1 {
+		t.Fatalf("RemoveExpired should remove 1 entry, but got %v with %v kept", n, arc.Len())
+	}
+}
+
+func TestArcMaxBytes(t *testing.T) {
+	sizeFunc := func(key Key, value interface{}) int64 {
+		return int64(len(key.(string)) + len(value.(string)))
+	}
+	arc, err := New("arc", Options{MaxBytes: 10, SizeFunc: sizeFunc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	arc.Add("k1", "v1")
+	arc.Add("k2", "v2")
+	arc.Get("k1")
+	arc.Add("k3", "v3")
+	if arc.Len() != 2 || arc.Bytes() != 8 {
+		t.Fatalf("arc should evict until under 10 bytes, but got %v entries, %v bytes", arc.Len(), arc.Bytes())
+	}
+	if _, ok := arc.Get("k1"); !ok {
+		t.Fatal("k1 in t2 should be kept")
+	}
+	if _, ok := arc.Get("k2"); ok {
+		t.Fatal("k2 should have been evicted")
+	}
+}
+
+// TestArcMaxBytesEmptyT2 在 b1 命中使 p 增大、t2 被淘汰空之后继续按字节淘汰，不能死循环
+func TestArcMaxBytesEmptyT2(t *testing.T) {
+	sizeFunc := func(key Key, value interface{}) int64 {
+		return int64(len(key.(string)) + len(value.(string)))
+	}
+	arc, err := New("arc", Options{MaxBytes: 20, SizeFunc: sizeFunc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for _, key := range keys {
+		arc.Add(key, "vvvv")
+	}
+	for _, key := range keys {
+		arc.Add(key, "vvvv")
+	}
+	arc.Add("g", strings.Repeat("x", 30))
+	if arc.Bytes() > 20 {
+		t.Fatalf("arc should hold at most 20 bytes, but got %v", arc.Bytes())
+	}
+}
+
+func TestArcRemove(t *testing.T) {
+	arc := NewARCache(2, nil)
+	arc.Add(1, 1)
+	arc.Add(2, 2)
+	arc.Get(2)
+	arc.Add(3, 3)
+	// t1 {3}, t2 {2}, b1 {1}
+	arc.Remove(1)
+	arc.Remove(2)
+	if arc.Len() != 1 || arc.b1.Len() != 0 {
+		t.Fatalf("arc should hold 1 element and no ghost, but got %v, %v", arc.Len(), arc.b1.Len())
+	}
+	if _, ok := arc.Get(2); ok {
+		t.Fatal("removed key should not be returned")
+	}
+}
+
+func TestArcOnEnvicted(t *testing.T) {
+	envictedKeys := make([]Key, 0)
+	arc := NewARCache(2, func(key Key, value interface{}) {
+		envictedKeys = append(envictedKeys, key)
+	})
+	arc.Add(1, 1)
+	arc.Add(2, 2)
+	arc.Add(3, 3)
+	if len(envictedKeys) != 1 || envictedKeys[0] != Key(1) {
+		t.Fatalf("1 should be envicted, but got %v", envictedKeys)
+	}
+}
@@ -1,29 +1,86 @@
 package purgekit
 
+import (
+	"fmt"
+	"time"
+)
+
+// 支持的淘汰策略
+const (
+	PolicyLRU = "lru"
+	PolicyLFU = "lfu"
+	PolicyARC = "arc"
+	// PolicyTinyLFU 是 W-TinyLFU，在 LRU 的基础上根据访问频率决定是否接纳新的条目
+	PolicyTinyLFU = "tinylfu"
+)
+
 // 运训任意可比较的类型作为键
 type Key interface{}
 
 // OnEnvictedFunc 是可选的参数，在移除缓存元素是调用
 type OnEnvictedFunc func(Key, interface{})
 
+// SizeFunc 计算一个条目占用的字节数，用于按字节数限制缓存大小
+type SizeFunc func(Key, interface{}) int64
+
 // Cache 接口向外开放
 type Cache interface {
 	Get(Key) (interface{}, bool)
 	Add(Key, interface{})
+	// AddWithExpire 添加一个在 expire 之后过期的条目，expire 为零值表示永不过期
+	AddWithExpire(Key, interface{}, time.Time)
+	// Remove 移除 key 对应的条目
+	Remove(Key)
+	// RemoveExpired 移除所有已经过期的条目，返回移除的数量
+	RemoveExpired() int
+	// Evict 按照淘汰策略移除一个条目
+	Evict() (Key, interface{}, bool)
 	Len() int
+	// Bytes 返回所有条目占用的字节数，没有设置 SizeFunc 时为 0
+	Bytes() int64
+}
+
+// Options 是创建缓存时的配置
+type Options struct {
+	MaxEntries int            // MaxEntries 是最大条目数，0 表示不限制
+	MaxBytes   int64          // MaxBytes 是最大字节数，0 表示不限制，需要配合 SizeFunc 使用
+	SizeFunc   SizeFunc       // SizeFunc 计算条目占用的字节数
+	OnEnvicted OnEnvictedFunc // OnEnvicted 在移除缓存元素时调用
 }
 
 // NewCache 根据 policy 选择实例化对应的缓存
-// 错误的 policy 将会返回 LRUCache 实例
-func NewCache(policy string, maxEntries int, onEnvicted OnEnvictedFunc) Cache {
+// 错误的 policy 将会返回错误
+func NewCache(policy string, maxEntries int, onEnvicted OnEnvictedFunc) (Cache, error) {
+	return New(policy, Options{MaxEntries: maxEntries, OnEnvicted: onEnvicted})
+}
+
+// New 根据 policy 和 opts 实例化对应的缓存
+// 错误的 policy 将会返回错误
+func New(policy string, opts Options) (Cache, error) {
+	cfg := Config[Key, interface{}]{
+		MaxEntries: opts.MaxEntries,
+		MaxBytes:   opts.MaxBytes,
+		SizeFunc:   opts.SizeFunc,
+		OnEnvicted: opts.OnEnvicted,
+	}
 	switch policy {
-	case "lru":
-		return &LRUCache{maxEntries: maxEntries, onEnvited: onEnvicted}
-	case "lfu":
-		return &LFUCache{maxEntries: maxEntries, onEnvicted: onEnvicted}
-	case "arc":
-		return &ARCache{maxEntries: maxEntries, onEnvicted: onEnvicted}
+	case PolicyLRU:
+		return &LRUCache{LRU: *NewLRU(cfg)}, nil
+	case PolicyLFU:
+		return &LFUCache{LFU: *NewLFU(cfg)}, nil
+	case PolicyARC:
+		return &ARCache{ARC: *NewARC(cfg)}, nil
+	case PolicyTinyLFU:
+		if err := checkTinyLFU(cfg); err != nil {
+			return nil, err
+		}
+		return &TinyLFUCache{TinyLFU: *NewTinyLFU(cfg)}, nil
 	default:
-		return &LRUCache{maxEntries: maxEntries, onEnvited: onEnvicted}
+		return nil, fmt.Errorf("unknown cache policy %q", policy)
 	}
 }
+
+// expired 判断 expire 在 now 时刻是否已经过期，零值表示永不过期
+func expired(expire time.Time, now time.Time) bool {
+	return !expire.IsZero() && !now.Before(expire)
+}
@@ -0,0 +1,119 @@
+package purgekit
+
+import (
+	"fmt"
+	"time"
+)
+
+// TypedCache 是泛型版本的 Cache，由 LRU、LFU、ARC 和 TinyLFU 实现
+// 键和值不需要类型断言，也不会为装箱分配额外的内存
+type TypedCache[K comparable, V any] interface {
+	Get(K) (V, bool)
+	Add(K, V)
+	// AddWithExpire 添加一个在 expire 之后过期的条目，expire 为零值表示永不过期
+	AddWithExpire(K, V, time.Time)
+	// Remove 移除 key 对应的条目
+	Remove(K)
+	// RemoveExpired 移除所有已经过期的条目，返回移除的数量
+	RemoveExpired() int
+	// Evict 按照淘汰策略移除一个条目
+	Evict() (K, V, bool)
+	Len() int
+	// Bytes 返回所有条目占用的字节数，没有设置 SizeFunc 时为 0
+	Bytes() int64
+}
+
+// Config 是创建泛型缓存时的配置
+type Config[K comparable, V any] struct {
+	MaxEntries int                        // MaxEntries 是最大条目数，0 表示不限制
+	MaxBytes   int64                      // MaxBytes 是最大字节数，0 表示不限制，需要配合 SizeFunc 使用
+	SizeFunc   func(key K, value V) int64 // SizeFunc 计算条目占用的字节数
+	OnEnvicted func(key K, value V)       // OnEnvicted 在移除缓存元素时调用
+	// Hash 计算 key 的哈希值，只在 TinyLFU 中使用
+	// 为空时 K 的底层类型需要是字符串、布尔值或数字，其他类型的 key 需要设置 Hash
+	Hash func(key K) uint64
+}
+
+// NewTyped 根据 policy 和 cfg 实例化对应的泛型缓存
+// 错误的 policy 将会返回错误
+func NewTyped[K comparable, V any](policy string, cfg Config[K, V]) (TypedCache[K, V], error) {
+	switch policy {
+	case PolicyLRU:
+		return NewLRU(cfg), nil
+	case PolicyLFU:
+		return NewLFU(cfg), nil
+	case PolicyARC:
+		return NewARC(cfg), nil
+	case PolicyTinyLFU:
+		if err := checkTinyLFU(cfg); err != nil {
+			return nil, err
+		}
+		return NewTinyLFU(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown cache policy %q", policy)
+	}
+}
+
+// node 是泛型缓存中链表的节点，键和值直接保存在节点中，不需要装箱
+type node[K comparable, V any] struct {
+	key    K
+	value  V
+	expire time.Time // expire 为零值表示永不过期
+	size   int64     // size 是条目占用的字节数
+	freq   int       // freq 是条目的访问频率，只在 LFU 中使用
+
+	prev, next *node[K, V]
+}
+
+// nodeList 是带哨兵节点的双向链表，零值可以直接使用，不能被复制
+type nodeList[K comparable, V any] struct {
+	root node[K, V]
+	len  int
+}
+
+func (l *nodeList[K, V]) lazyInit() {
+	if l.root.next == nil {
+		l.root.next = &l.root
+		l.root.prev = &l.root
+	}
+}
+
+func (l *nodeList[K, V]) pushFront(n *node[K, V]) {
+	l.lazyInit()
+	n.prev = &l.root
+	n.next = l.root.next
+	l.root.next.prev = n
+	l.root.next = n
+	l.len++
+}
+
+func (l *nodeList[K, V]) remove(n *node[K, V]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev, n.next = nil, nil
+	l.len--
+}
+
+func (l *nodeList[K, V]) moveToFront(n *node[K, V]) {
+	if l.root.next == n {
+		return
+	}
+	l.remove(n)
+	l.pushFront(n)
+}
+
+// back 返回链表的最后一个节点，链表为空时返回 nil
+func (l *nodeList[K, V]) back() *node[K, V] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// prevOf 返回 n 的前一个节点，n 是第一个节点时返回 nil
+func (l *nodeList[K, V]) prevOf(n *node[K, V]) *node[K, V] {
+	if n.prev == &l.root {
+		return nil
+	}
+	return n.prev
+}
@@ -0,0 +1,265 @@
+package purgekit
+
+import "time"
+
+// ARC 算法使用一个额外的 LRU 缓存保存频率信息
+// 根据缓存命中情况,动态调整二者之间的比例
+type ARC[K comparable, V any] struct {
+	maxEntries int                  // maxEntries 保存有效缓存总大小, 0 表示不限制
+	maxBytes   int64                // maxBytes 是有效缓存可存储的最大字节数, 0 表示不限制
+	onEnvicted func(key K, value V) // onEnvicted 在有效缓存中的条目被移除时调用
+
+	p  int               // p 是缓存中 t1 的长度
+	t1 *LRU[K, V]        // t1 保存只出现一次的缓存数据
+	b1 *LRU[K, struct{}] // b1 保存 t1 中淘汰下来的缓存
+	t2 *LRU[K, V]        // t2 保存请求超过一次的数据
+	b2 *LRU[K, struct{}] // b2 保存 t2 中淘汰的键信息,用来动态调整 p 值
+}
+
+// NewARC 返回一个 ARC 实例
+func NewARC[K comparable, V any](cfg Config[K, V]) *ARC[K, V] {
+	lists := Config[K, V]{MaxEntries: cfg.MaxEntries, SizeFunc: cfg.SizeFunc}
+	ghosts := Config[K, struct{}]{MaxEntries: cfg.MaxEntries}
+	return &ARC[K, V]{
+		maxEntries: cfg.MaxEntries,
+		maxBytes:   cfg.MaxBytes,
+		onEnvicted: cfg.OnEnvicted,
+		t1:         NewLRU(lists),
+		t2:         NewLRU(lists),
+		b1:         NewLRU(ghosts),
+		b2:         NewLRU(ghosts),
+	}
+}
+
+// Get 查找当前缓存,返回对应的缓存值(如果存在),和一个代表操作是否成功的布尔值
+func (c *ARC[K, V]) Get(key K) (value V, ok bool) {
+	// t1 中找到,移动到 t2, 已经过期的直接丢弃
+	if n, ok := c.t1.cache[key]; ok {
+		c.t1.Remove(key)
+		if expired(n.expire, time.Now()) {
+			c.envicted(key, n.value)
+			return value, false
+		}
+		c.t2.AddWithExpire(key, n.value, n.expire)
+		return n.value, true
+	}
+	// t2 中找到,移动到 t2 队首, 已经过期的直接丢弃
+	if n, ok := c.t2.cache[key]; ok {
+		if expired(n.expire, time.Now()) {
+			c.t2.Remove(key)
+			c.envicted(key, n.value)
+			return value, false
+		}
+		c.t2.ll.moveToFront(n)
+		return n.value, true
+	}
+	return
+}
+
+// Add 添加一个新缓存或者更新值
+func (c *ARC[K, V]) Add(key K, value V) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 添加一个在 expire 之后过期的缓存或者更新值
+func (c *ARC[K, V]) AddWithExpire(key K, value V, expire time.Time) {
+	c.add(key, value, expire)
+	// 超出字节限制时, 按照 ARC 策略持续淘汰, 没有可以淘汰的条目时停止
+	for c.maxBytes != 0 && c.Bytes() > c.maxBytes {
+		if _, _, ok := c.replace(false); !ok {
+			break
+		}
+	}
+	c.trimGhosts()
+}
+
+// add 按照 ARC 策略添加条目, 只检查条目数限制
+func (c *ARC[K, V]) add(key K, value V, expire time.Time) {
+	// t1 中找到,移动到 t2
+	if c.t1.Contains(key) {
+		c.t1.Remove(key)
+		c.t2.AddWithExpire(key, value, expire)
+		return
+	}
+	// t2 中找到,移动到 t2 队首
+	if c.t2.Contains(key) {
+		c.t2.AddWithExpire(key, value, expire)
+		return
+	}
+
+	// b1中找到,说明 t1 太小
+	if c.b1.Contains(key) {
+		// b1 > b2 时,p 只需要增加 1
+		delta := 1
+		b1len := c.b1.Len()
+		b2len := c.b2.Len()
+		// b1 < b2 时, p 需要大幅度调整
+		if b2len > b1len {
+			delta = b2len / b1len
+		}
+		// 如果调整后的 t1 长度超过总长度,设置为最大长度
+		if capacity := c.capacity(); c.p+delta >= capacity {
+			c.p = capacity
+		} else {
+			c.p += delta
+		}
+		// 调整后, 如果 t1 + t2 超出总长度, 应当淘汰数据
+		if c.full() {
+			c.replace(false)
+		}
+		// 从淘汰记录中删除当前 key
+		c.b1.Remove(key)
+		c.t2.AddWithExpire(key, value, expire)
+		return
+	}
+
+	// b2 中找到, 说明 t2 过小, 应当减小 p 值
+	if c.b2.Contains(key) {
+		delta := 1
+		b1len := c.b1.Len()
+		b2len := c.b2.Len()
+		if b1len > b2len {
+			delta = b1len / b2len
+		}
+		// 调整后 p 小于 0, 设置为 0
+		if delta >= c.p {
+			c.p = 0
+		} else {
+			c.p -= delta
+		}
+		// 调整后有效缓存超过总长度, 淘汰数据
+		if c.full() {
+			c.replace(true)
+		}
+		c.b2.Remove(key)
+		c.t2.AddWithExpire(key, value, expire)
+		return
+	}
+	// t1 t2 b1 b2 都没有, 需要添加新条目
+	// 如果没有空间, 先淘汰
+	if c.full() {
+		c.replace(false)
+	}
+	if c.b1.Len() > c.capacity()-c.p {
+		c.b1.RemoveOldest()
+	}
+	if c.b2.Len() > c.p {
+		c.b2.RemoveOldest()
+	}
+	// 添加新条目
+	c.t1.AddWithExpire(key, value, expire)
+}
+
+// Remove 移除 key 对应的缓存, 同时清除 b1 和 b2 中的记录
+func (c *ARC[K, V]) Remove(key K) {
+	for _, l := range []*LRU[K, V]{c.t1, c.t2} {
+		if n, ok := l.cache[key]; ok {
+			l.removeNode(n)
+			c.envicted(key, n.value)
+		}
+	}
+	c.b1.Remove(key)
+	c.b2.Remove(key)
+}
+
+// RemoveExpired 移除 t1 和 t2 中所有已经过期的条目
+// 过期不是容量淘汰, 不会记录到 b1 和 b2 中
+func (c *ARC[K, V]) RemoveExpired() int {
+	now, count := time.Now(), 0
+	for _, l := range []*LRU[K, V]{c.t1, c.t2} {
+		for n := l.ll.back(); n != nil; {
+			prev := l.ll.prevOf(n)
+			if expired(n.expire, now) {
+				l.removeNode(n)
+				c.envicted(n.key, n.value)
+				count++
+			}
+			n = prev
+		}
+	}
+	return count
+}
+
+// envicted 在有效缓存中的条目被移除时调用 onEnvicted
+func (c *ARC[K, V]) envicted(key K, value V) {
+	if c.onEnvicted != nil {
+		c.onEnvicted(key, value)
+	}
+}
+
+// replace 根据情况选择不同队列淘汰
+// 如果 t1 超出当前 p 值, 并且 t2 过小,从 t1 中淘汰
+// 否则从 t2 中淘汰, 选中的队列为空时从另一个队列淘汰
+// 返回被淘汰的条目, 两个队列都为空时 ok 为 false
+func (c *ARC[K, V]) replace(contains bool) (key K, value V, ok bool) {
+	t1len := c.t1.Len()
+	fromT1 := t1len > 0 && (t1len > c.p || (t1len == c.p && contains))
+	if !fromT1 && c.t2.Len() == 0 {
+		fromT1 = true
+	}
+	if fromT1 {
+		key, value, ok = c.t1.RemoveOldest()
+		if ok {
+			c.b1.Add(key, struct{}{})
+		}
+	} else {
+		key, value, ok = c.t2.RemoveOldest()
+		if ok {
+			c.b2.Add(key, struct{}{})
+		}
+	}
+	if ok {
+		c.envicted(key, value)
+	}
+	return
+}
+
+// full 判断有效缓存是否已经达到条目数限制
+func (c *ARC[K, V]) full() bool {
+	return c.maxEntries != 0 && c.t1.Len()+c.t2.Len() >= c.maxEntries
+}
+
+// capacity 返回用于调整 p 的缓存容量
+// 没有条目数限制时, 使用当前有效缓存的数量
+func (c *ARC[K, V]) capacity() int {
+	if c.maxEntries != 0 {
+		return c.maxEntries
+	}
+	return c.Len()
+}
+
+// trimGhosts 在没有条目数限制时, 保证 b1 + b2 不超过有效缓存的数量
+func (c *ARC[K, V]) trimGhosts() {
+	if c.maxEntries != 0 {
+		return
+	}
+	for c.b1.Len()+c.b2.Len() > c.Len() {
+		if c.b1.Len() > c.b2.Len() {
+			c.b1.RemoveOldest()
+		} else {
+			c.b2.RemoveOldest()
+		}
+	}
+}
+
+// Evict 按照 ARC 策略淘汰一个条目, 被淘汰的键记录到 b1 或 b2 中
+func (c *ARC[K, V]) Evict() (key K, value V, ok bool) {
+	if c.Len() == 0 {
+		return
+	}
+	key, value, ok = c.replace(false)
+	c.trimGhosts()
+	return
+}
+
+// Len 返回当期有效缓存的数量
+func (c *ARC[K, V]) Len() int {
+	return c.t1.Len() + c.t2.Len()
+}
+
+// Bytes 返回当前有效缓存占用的字节数
+func (c *ARC[K, V]) Bytes() int64 {
+	return c.t1.Bytes() + c.t2.Bytes()
+}
+
+var _ TypedCache[string, int] = (*ARC[string, int])(nil)
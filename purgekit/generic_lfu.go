@@ -0,0 +1,207 @@
+package purgekit
+
+import "time"
+
+// LFU 是实现了 LFU 淘汰机制的泛型缓存，访问频率相同时淘汰最久未访问的条目
+type LFU[K comparable, V any] struct {
+	maxEntries int
+	maxBytes   int64                      // maxBytes 是缓存可存储的最大字节数，0 表示不限制
+	sizeFunc   func(key K, value V) int64 // sizeFunc 计算条目占用的字节数
+	onEnvicted func(key K, value V)
+
+	freqList map[int]*freqBucket[K, V] // freqList 只保存非空的频率链表
+	cache    map[K]*node[K, V]
+	minFreq  int   // minFreq 是最低的访问频率，缓存为空时为 -1
+	nbytes   int64 // nbytes 是当前所有条目占用的字节数
+}
+
+// freqBucket 是同一访问频率的条目链表，所有非空的 freqBucket 按频率从低到高串联
+// 访问频率变化时只需要查看相邻的 freqBucket，不需要扫描所有频率
+type freqBucket[K comparable, V any] struct {
+	nodeList[K, V]
+	freq       int
+	prev, next *freqBucket[K, V]
+}
+
+// NewLFU 返回一个 LFU 实例
+func NewLFU[K comparable, V any](cfg Config[K, V]) *LFU[K, V] {
+	return &LFU[K, V]{
+		maxEntries: cfg.MaxEntries,
+		maxBytes:   cfg.MaxBytes,
+		sizeFunc:   cfg.SizeFunc,
+		onEnvicted: cfg.OnEnvicted,
+		freqList:   make(map[int]*freqBucket[K, V]),
+		cache:      make(map[K]*node[K, V]),
+		minFreq:    -1,
+	}
+}
+
+// Get 返回 key 对应的值（如果存在），和一个表示值是否存在的布尔值
+func (c *LFU[K, V]) Get(key K) (value V, ok bool) {
+	n, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	if expired(n.expire, time.Now()) {
+		c.Remove(key)
+		return value, false
+	}
+	c.jump(n)
+	return n.value, true
+}
+
+// Add 添加一个键值对到缓存中（如果之前不存在），或者更新一个键值对（之前已经存在）
+func (c *LFU[K, V]) Add(key K, value V) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 添加或更新一个在 expire 之后过期的键值对
+func (c *LFU[K, V]) AddWithExpire(key K, value V, expire time.Time) {
+	var size int64
+	if c.sizeFunc != nil {
+		size = c.sizeFunc(key, value)
+	}
+	if n, ok := c.cache[key]; ok {
+		c.nbytes += size - n.size
+		n.value = value
+		n.expire = expire
+		n.size = size
+		c.jump(n)
+	} else {
+		n := &node[K, V]{key: key, value: value, expire: expire, size: size}
+		c.bucket(0, nil).pushFront(n)
+		c.cache[key] = n
+		c.nbytes += size
+	}
+	for c.overflow() {
+		c.RemoveLeastUsed()
+	}
+}
+
+// bucket 返回频率为 freq 的链表，不存在时创建并插入到 prev 之后
+// prev 为 nil 时插入到最前面，调用方保证 freq 大于 prev 的频率并小于 prev 之后的频率
+func (c *LFU[K, V]) bucket(freq int, prev *freqBucket[K, V]) *freqBucket[K, V] {
+	if b := c.freqList[freq]; b != nil {
+		return b
+	}
+	b := &freqBucket[K, V]{freq: freq, prev: prev}
+	if prev != nil {
+		b.next = prev.next
+		prev.next = b
+	} else {
+		b.next = c.freqList[c.minFreq]
+		c.minFreq = freq
+	}
+	if b.next != nil {
+		b.next.prev = b
+	}
+	c.freqList[freq] = b
+	return b
+}
+
+// unlink 在 b 为空时把它从频率链表中删除，并在需要时更新 minFreq
+func (c *LFU[K, V]) unlink(b *freqBucket[K, V]) {
+	if b.len > 0 {
+		return
+	}
+	if b.prev != nil {
+		b.prev.next = b.next
+	}
+	if b.next != nil {
+		b.next.prev = b.prev
+	}
+	if b.freq == c.minFreq {
+		c.minFreq = -1
+		if b.next != nil {
+			c.minFreq = b.next.freq
+		}
+	}
+	delete(c.freqList, b.freq)
+}
+
+// overflow 判断缓存是否超出了条目数或字节数的限制
+func (c *LFU[K, V]) overflow() bool {
+	if len(c.cache) == 0 {
+		return false
+	}
+	return (c.maxEntries != 0 && len(c.cache) > c.maxEntries) ||
+		(c.maxBytes != 0 && c.nbytes > c.maxBytes)
+}
+
+// Remove 移除指定的 Key
+func (c *LFU[K, V]) Remove(key K) {
+	if n, ok := c.cache[key]; ok {
+		c.removeNode(n)
+	}
+}
+
+// removeNode 移除节点，节点所在的频率链表为空时一并删除
+func (c *LFU[K, V]) removeNode(n *node[K, V]) {
+	b := c.freqList[n.freq]
+	b.remove(n)
+	c.unlink(b)
+	delete(c.cache, n.key)
+	c.nbytes -= n.size
+	if c.onEnvicted != nil {
+		c.onEnvicted(n.key, n.value)
+	}
+}
+
+// RemoveExpired 移除所有已经过期的条目
+func (c *LFU[K, V]) RemoveExpired() int {
+	now, count := time.Now(), 0
+	for _, n := range c.cache {
+		if expired(n.expire, now) {
+			c.removeNode(n)
+			count++
+		}
+	}
+	return count
+}
+
+// RemoveLeastUsed 移除使用频率最少，使用时间最久远的键值对
+func (c *LFU[K, V]) RemoveLeastUsed() (key K, value V, ok bool) {
+	if len(c.cache) == 0 {
+		return
+	}
+	n := c.freqList[c.minFreq].back()
+	c.removeNode(n)
+	return n.key, n.value, true
+}
+
+// Evict 按照 LFU 策略淘汰一个条目
+func (c *LFU[K, V]) Evict() (key K, value V, ok bool) {
+	return c.RemoveLeastUsed()
+}
+
+func (c *LFU[K, V]) Len() int {
+	return len(c.cache)
+}
+
+// Bytes 返回当前所有条目占用的字节数
+func (c *LFU[K, V]) Bytes() int64 {
+	return c.nbytes
+}
+
+// jump 将节点提升到频率加一的链表里，原来的链表为空时删除
+func (c *LFU[K, V]) jump(n *node[K, V]) {
+	b := c.freqList[n.freq]
+	b.remove(n)
+	n.freq += 1
+	c.bucket(n.freq, b).pushFront(n)
+	c.unlink(b)
+}
+
+func (c *LFU[K, V]) Contains(key K) bool {
+	_, ok := c.cache[key]
+	return ok
+}
+
+func (c *LFU[K, V]) Peek(key K) (value V, ok bool) {
+	if n, ok := c.cache[key]; ok {
+		return n.value, true
+	}
+	return
+}
+
+var _ TypedCache[string, int] = (*LFU[string, int])(nil)
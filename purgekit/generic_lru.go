@@ -0,0 +1,161 @@
+package purgekit
+
+import "time"
+
+// LRU 是实现了 LRU 淘汰机制的泛型缓存
+type LRU[K comparable, V any] struct {
+	maxEntries int                        // maxEntries 是缓存可存储的最大条目数
+	maxBytes   int64                      // maxBytes 是缓存可存储的最大字节数，0 表示不限制
+	sizeFunc   func(key K, value V) int64 // sizeFunc 计算条目占用的字节数
+	onEnvicted func(key K, value V)       // 淘汰条目时进行的额外操作
+
+	ll     nodeList[K, V]
+	cache  map[K]*node[K, V]
+	nbytes int64 // nbytes 是当前所有条目占用的字节数
+}
+
+// NewLRU 返回一个 LRU 实例
+func NewLRU[K comparable, V any](cfg Config[K, V]) *LRU[K, V] {
+	return &LRU[K, V]{
+		maxEntries: cfg.MaxEntries,
+		maxBytes:   cfg.MaxBytes,
+		sizeFunc:   cfg.SizeFunc,
+		onEnvicted: cfg.OnEnvicted,
+		cache:      make(map[K]*node[K, V]),
+	}
+}
+
+// Get 返回缓存中对应的值（如果存在）和一个表示是否存在的布尔值
+func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	n, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	if expired(n.expire, time.Now()) {
+		c.removeNode(n)
+		return value, false
+	}
+	c.ll.moveToFront(n)
+	return n.value, true
+}
+
+// Add 向缓存中添加键值对（不存在）或更新键值对（已存在）
+func (c *LRU[K, V]) Add(key K, value V) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 向缓存中添加或更新一个在 expire 之后过期的键值对
+func (c *LRU[K, V]) AddWithExpire(key K, value V, expire time.Time) {
+	if c.cache == nil {
+		c.cache = make(map[K]*node[K, V])
+	}
+	var size int64
+	if c.sizeFunc != nil {
+		size = c.sizeFunc(key, value)
+	}
+	if n, ok := c.cache[key]; ok {
+		c.ll.moveToFront(n)
+		c.nbytes += size - n.size
+		n.value = value
+		n.expire = expire
+		n.size = size
+	} else {
+		n := &node[K, V]{key: key, value: value, expire: expire, size: size}
+		c.ll.pushFront(n)
+		c.cache[key] = n
+		c.nbytes += size
+	}
+	for c.overflow() {
+		c.RemoveOldest()
+	}
+}
+
+// overflow 判断缓存是否超出了条目数或字节数的限制
+func (c *LRU[K, V]) overflow() bool {
+	if c.ll.len == 0 {
+		return false
+	}
+	return (c.maxEntries != 0 && c.ll.len > c.maxEntries) ||
+		(c.maxBytes != 0 && c.nbytes > c.maxBytes)
+}
+
+// Remove 移除给定键对应的缓存
+func (c *LRU[K, V]) Remove(key K) {
+	if n, ok := c.cache[key]; ok {
+		c.removeNode(n)
+	}
+}
+
+// RemoveOldest 从缓存中移除缓存中最老的条目
+func (c *LRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	n := c.ll.back()
+	if n == nil {
+		return
+	}
+	c.removeNode(n)
+	return n.key, n.value, true
+}
+
+// RemoveExpired 移除所有已经过期的条目
+func (c *LRU[K, V]) RemoveExpired() int {
+	now, count := time.Now(), 0
+	for n := c.ll.back(); n != nil; {
+		prev := c.ll.prevOf(n)
+		if expired(n.expire, now) {
+			c.removeNode(n)
+			count++
+		}
+		n = prev
+	}
+	return count
+}
+
+// Evict 按照 LRU 策略淘汰一个条目
+func (c *LRU[K, V]) Evict() (key K, value V, ok bool) {
+	return c.RemoveOldest()
+}
+
+// removeNode 从缓存中删除指定的节点
+func (c *LRU[K, V]) removeNode(n *node[K, V]) {
+	c.ll.remove(n)
+	delete(c.cache, n.key)
+	c.nbytes -= n.size
+	if c.onEnvicted != nil {
+		c.onEnvicted(n.key, n.value)
+	}
+}
+
+// Len 返回当前缓存中的条目数
+func (c *LRU[K, V]) Len() int {
+	return c.ll.len
+}
+
+// Clear 移除所有的缓存，不会调用 OnEnvicted
+func (c *LRU[K, V]) Clear() {
+	c.cache = nil
+	c.ll = nodeList[K, V]{}
+	c.nbytes = 0
+}
+
+// Bytes 返回当前所有条目占用的字节数
+func (c *LRU[K, V]) Bytes() int64 {
+	return c.nbytes
+}
+
+// Contains 判断 key 是否在缓存中，不会改变缓存的状态
+func (c *LRU[K, V]) Contains(key K) bool {
+	_, ok := c.cache[key]
+	return ok
+}
+
+// Peek 获取对应的缓存而不会改变缓存的状态
+// 已经过期的条目视为不存在
+func (c *LRU[K, V]) Peek(key K) (value V, ok bool) {
+	n, ok := c.cache[key]
+	if !ok || expired(n.expire, time.Now()) {
+		return value, false
+	}
+	return n.value, true
+}
+
+var _ TypedCache[string, int] = (*LRU[string, int])(nil)
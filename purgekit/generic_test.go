@@ -0,0 +1,231 @@
+package purgekit
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenericLRU(t *testing.T) {
+	var evicted []string
+	lru := NewLRU(Config[string, int]{
+		MaxEntries: 2,
+		OnEnvicted: func(key string, value int) { evicted = append(evicted, key) },
+	})
+	lru.Add("k1", 1)
+	lru.Add("k2", 2)
+	lru.Get("k1")
+	lru.Add("k3", 3)
+	if _, ok := lru.Get("k2"); ok {
+		t.Fatal("k2 should be evicted as the least recently used key")
+	}
+	if v, ok := lru.Get("k1"); !ok || v != 1 {
+		t.Fatalf("want k1=1, but got %d, %v", v, ok)
+	}
+	if len(evicted) != 1 || evicted[0] != "k2" {
+		t.Fatalf("want k2 evicted, but got %v", evicted)
+	}
+
+	lru.AddWithExpire("k4", 4, time.Now().Add(-time.Second))
+	if _, ok := lru.Get("k4"); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+	lru.Clear()
+	if lru.Len() != 0 || lru.Bytes() != 0 {
+		t.Fatal("cache should be empty after Clear")
+	}
+	lru.Add("k5", 5)
+	if v, ok := lru.Get("k5"); !ok || v != 5 {
+		t.Fatal("cache should be usable after Clear")
+	}
+}
+
+func TestGenericMaxBytes(t *testing.T) {
+	size := func(key string, value string) int64 { return int64(len(key) + len(value)) }
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		cfg := Config[string, string]{MaxBytes: 10, SizeFunc: size}
+		if policy == PolicyTinyLFU {
+			// TinyLFU 需要条目数限制来划分窗口和主缓存
+			if _, err := NewTyped(policy, cfg); err == nil {
+				t.Fatal("tinylfu without MaxEntries should be rejected")
+			}
+			cfg.MaxEntries = 100
+		}
+		c, err := NewTyped(policy, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Add("k1", "1234")
+		c.Add("k2", "1234")
+		if c.Bytes() > 10 || c.Len() != 1 {
+			t.Fatalf("%s: want 1 entry within 10 bytes, but got %d entries, %d bytes", policy, c.Len(), c.Bytes())
+		}
+		if _, ok := c.Get("k2"); !ok {
+			t.Fatalf("%s: newest entry should be kept", policy)
+		}
+	}
+	if _, err := NewTyped[string, string]("unknown", Config[string, string]{}); err == nil {
+		t.Fatal("unknown policy should be rejected")
+	}
+}
+
+// TestGenericSameSemantics 使用随机的操作序列比较泛型缓存与原有缓存的行为
+// 分别按条目数和字节数限制缓存大小
+func TestGenericSameSemantics(t *testing.T) {
+	for _, limit := range []struct {
+		name       string
+		maxEntries int
+		maxBytes   int64
+	}{
+		{"entries", 64, 0},
+		{"bytes", 0, 640},
+		{"both", 64, 640},
+	} {
+		for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+			if policy == PolicyTinyLFU && limit.maxEntries == 0 {
+				continue
+			}
+			var oldEvicted, newEvicted []int
+			old, _ := New(policy, Options{
+				MaxEntries: limit.maxEntries,
+				MaxBytes:   limit.maxBytes,
+				SizeFunc:   func(key Key, value interface{}) int64 { return int64(value.(int)%20 + 1) },
+				OnEnvicted: func(key Key, value interface{}) { oldEvicted = append(oldEvicted, key.(int)) },
+			})
+			typed, _ := NewTyped(policy, Config[int, int]{
+				MaxEntries: limit.maxEntries,
+				MaxBytes:   limit.maxBytes,
+				SizeFunc:   func(key int, value int) int64 { return int64(value%20 + 1) },
+				OnEnvicted: func(key int, value int) { newEvicted = append(newEvicted, key) },
+			})
+			name := policy + "/" + limit.name
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < 10000; i++ {
+				key := int(r.ExpFloat64() * 50)
+				switch r.Intn(10) {
+				case 0:
+					old.Remove(key)
+					typed.Remove(key)
+				case 1, 2, 3:
+					old.Add(key, i)
+					typed.Add(key, i)
+				default:
+					ov, ook := old.Get(key)
+					nv, nok := typed.Get(key)
+					if ook != nok || (ook && ov.(int) != nv) {
+						t.Fatalf("%s: step %d get %d: want %v, %v, but got %v, %v", name, i, key, ov, ook, nv, nok)
+					}
+				}
+				if old.Len() != typed.Len() || old.Bytes() != typed.Bytes() {
+					t.Fatalf("%s: step %d: want %d entries, %d bytes, but got %d, %d",
+						name, i, old.Len(), old.Bytes(), typed.Len(), typed.Bytes())
+				}
+				if limit.maxBytes != 0 && typed.Bytes() > limit.maxBytes {
+					t.Fatalf("%s: step %d: %d bytes exceed the limit", name, i, typed.Bytes())
+				}
+			}
+			if fmt.Sprint(oldEvicted) != fmt.Sprint(newEvicted) {
+				t.Fatalf("%s: evicted keys differ", name)
+			}
+		}
+	}
+}
+
+// TestGenericARCMaxBytesEmptyT2 与 TestArcMaxBytesEmptyT2 相同，使用泛型的 ARC
+func TestGenericARCMaxBytesEmptyT2(t *testing.T) {
+	c, _ := NewTyped(PolicyARC, Config[string, string]{
+		MaxBytes: 20,
+		SizeFunc: func(key string, value string) int64 { return int64(len(key) + len(value)) },
+	})
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for _, key := range keys {
+		c.Add(key, "vvvv")
+	}
+	for _, key := range keys {
+		c.Add(key, "vvvv")
+	}
+	c.Add("g", strings.Repeat("x", 30))
+	if c.Bytes() > 20 {
+		t.Fatalf("arc should hold at most 20 bytes, but got %v", c.Bytes())
+	}
+}
+
+type benchValue struct {
+	b []byte
+	s string
+	e time.Time
+}
+
+func benchKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	return keys
+}
+
+// 比较原有缓存和泛型缓存在写入和读取时的内存分配
+
+func BenchmarkCacheAdd(b *testing.B) {
+	keys := benchKeys(1024)
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		b.Run(policy, func(b *testing.B) {
+			c, _ := New(policy, Options{MaxEntries: 512})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Add(keys[i%len(keys)], benchValue{s: "value"})
+			}
+		})
+	}
+}
+
+func BenchmarkGenericAdd(b *testing.B) {
+	keys := benchKeys(1024)
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		b.Run(policy, func(b *testing.B) {
+			c, _ := NewTyped(policy, Config[string, benchValue]{MaxEntries: 512})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Add(keys[i%len(keys)], benchValue{s: "value"})
+			}
+		})
+	}
+}
+
+func BenchmarkCacheGet(b *testing.B) {
+	keys := benchKeys(512)
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		b.Run(policy, func(b *testing.B) {
+			c, _ := New(policy, Options{})
+			for _, key := range keys {
+				c.Add(key, benchValue{s: "value"})
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if v, ok := c.Get(keys[i%len(keys)]); ok {
+					_ = v.(benchValue)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGenericGet(b *testing.B) {
+	keys := benchKeys(512)
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		b.Run(policy, func(b *testing.B) {
+			c, _ := NewTyped(policy, Config[string, benchValue]{})
+			for _, key := range keys {
+				c.Add(key, benchValue{s: "value"})
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Get(keys[i%len(keys)])
+			}
+		})
+	}
+}
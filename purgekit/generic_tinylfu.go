@@ -0,0 +1,235 @@
+package purgekit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// tinyLFUWindowPercent 是窗口 LRU 占总容量的百分比
+	tinyLFUWindowPercent = 1
+	// tinyLFUProtectedPercent 是 protected 分段占主缓存容量的百分比
+	tinyLFUProtectedPercent = 80
+)
+
+// TinyLFU 是 W-TinyLFU 淘汰策略 (Einziger & Friedman)
+// 新的条目先进入很小的窗口 LRU，从窗口淘汰的条目只有在 count-min sketch 估计的访问频率
+// 高于主缓存中将被淘汰的条目时才会被接纳，因此偶尔出现的 key 和顺序扫描不会冲掉热点数据。
+// 主缓存是分段 LRU：新接纳的条目进入 probation，再次访问后晋升到 protected，
+// protected 超出容量时最久未访问的条目降级回 probation。
+// 没有条目数限制时窗口不限大小，此时与 LRU 相同，因此不接受只按字节数限制的 TinyLFU
+type TinyLFU[K comparable, V any] struct {
+	maxEntries int                  // maxEntries 是缓存可存储的最大条目数，0 表示不限制
+	maxBytes   int64                // maxBytes 是缓存可存储的最大字节数，0 表示不限制
+	onEnvicted func(key K, value V) // onEnvicted 在条目被移除时调用
+
+	windowCap    int        // windowCap 是窗口的最大条目数
+	protectedCap int        // protectedCap 是 protected 的最大条目数
+	window       *LRU[K, V] // window 保存新加入的条目
+	probation    *LRU[K, V] // probation 保存被接纳、之后还没有被访问过的条目
+	protected    *LRU[K, V] // protected 保存在主缓存中被再次访问过的条目
+	sketch       *cmSketch  // sketch 估计 key 的访问频率
+	hash         func(K) uint64
+}
+
+// errTinyLFUMaxBytes 表示只按字节数限制了 TinyLFU 的容量
+// 窗口和主缓存的大小按条目数划分，没有条目数限制时 TinyLFU 退化为 LRU
+var errTinyLFUMaxBytes = errors.New("purgekit: tinylfu requires MaxEntries when MaxBytes is set")
+
+// checkTinyLFU 检查 cfg 能否用于创建 TinyLFU
+func checkTinyLFU[K comparable, V any](cfg Config[K, V]) error {
+	if cfg.MaxEntries == 0 && cfg.MaxBytes != 0 {
+		return errTinyLFUMaxBytes
+	}
+	if cfg.Hash == nil {
+		if _, ok := keyHasher[K](); !ok {
+			var key K
+			return fmt.Errorf("purgekit: tinylfu requires Config.Hash for key type %T", key)
+		}
+	}
+	return nil
+}
+
+// NewTinyLFU 返回一个 TinyLFU 实例
+// cfg 只设置了 MaxBytes，或者 key 的类型需要 Hash 却没有设置时 panic，NewTyped 在这些情况下返回错误
+func NewTinyLFU[K comparable, V any](cfg Config[K, V]) *TinyLFU[K, V] {
+	if err := checkTinyLFU(cfg); err != nil {
+		panic(err)
+	}
+	hash := cfg.Hash
+	if hash == nil {
+		hash, _ = keyHasher[K]()
+	}
+	windowCap, protectedCap := tinyLFUCaps(cfg.MaxEntries)
+	segments := Config[K, V]{SizeFunc: cfg.SizeFunc}
+	return &TinyLFU[K, V]{
+		maxEntries:   cfg.MaxEntries,
+		maxBytes:     cfg.MaxBytes,
+		onEnvicted:   cfg.OnEnvicted,
+		windowCap:    windowCap,
+		protectedCap: protectedCap,
+		window:       NewLRU(segments),
+		probation:    NewLRU(segments),
+		protected:    NewLRU(segments),
+		sketch:       newCMSketch(cfg.MaxEntries),
+		hash:         hash,
+	}
+}
+
+// tinyLFUCaps 根据总容量计算窗口和 protected 的容量
+func tinyLFUCaps(maxEntries int) (windowCap, protectedCap int) {
+	if maxEntries == 0 {
+		return 0, 0
+	}
+	windowCap = maxEntries * tinyLFUWindowPercent / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	protectedCap = (maxEntries - windowCap) * tinyLFUProtectedPercent / 100
+	return windowCap, protectedCap
+}
+
+// Get 返回 key 对应的值（如果存在），和一个表示值是否存在的布尔值
+// 无论是否命中都会记录一次访问
+func (c *TinyLFU[K, V]) Get(key K) (value V, ok bool) {
+	c.sketch.increment(c.hash(key))
+	l := c.segmentOf(key)
+	if l == nil {
+		return
+	}
+	n := l.cache[key]
+	if expired(n.expire, time.Now()) {
+		c.evictNode(l, n)
+		return value, false
+	}
+	if l == c.probation {
+		// 在主缓存中再次被访问，晋升到 protected
+		c.move(c.probation, c.protected, n)
+		for c.protected.Len() > c.protectedCap {
+			c.move(c.protected, c.probation, c.protected.ll.back())
+		}
+	} else {
+		l.ll.moveToFront(n)
+	}
+	return n.value, true
+}
+
+// Add 添加一个键值对到缓存中（如果之前不存在），或者更新一个键值对（之前已经存在）
+func (c *TinyLFU[K, V]) Add(key K, value V) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 添加或更新一个在 expire 之后过期的键值对
+// 新的条目总是先加入窗口，窗口满了之后由 sketch 决定接纳窗口淘汰的条目还是主缓存淘汰的条目
+func (c *TinyLFU[K, V]) AddWithExpire(key K, value V, expire time.Time) {
+	c.sketch.increment(c.hash(key))
+	if l := c.segmentOf(key); l != nil {
+		l.AddWithExpire(key, value, expire)
+	} else {
+		c.window.AddWithExpire(key, value, expire)
+		for c.maxEntries != 0 && c.window.Len() > c.windowCap {
+			c.admit(c.window.ll.back())
+		}
+	}
+	for c.maxBytes != 0 && c.Bytes() > c.maxBytes && c.Len() > 0 {
+		c.Evict()
+	}
+}
+
+// admit 将窗口中的候选条目移入主缓存，主缓存已满时只保留候选条目和淘汰条目中访问频率更高的一个
+func (c *TinyLFU[K, V]) admit(candidate *node[K, V]) {
+	if c.probation.Len()+c.protected.Len() < c.maxEntries-c.windowCap {
+		c.move(c.window, c.probation, candidate)
+		return
+	}
+	victims := c.probation
+	if victims.Len() == 0 {
+		victims = c.protected
+	}
+	victim := victims.ll.back()
+	if victim == nil {
+		c.evictNode(c.window, candidate)
+		return
+	}
+	if c.sketch.estimate(c.hash(candidate.key)) > c.sketch.estimate(c.hash(victim.key)) {
+		c.evictNode(victims, victim)
+		c.move(c.window, c.probation, candidate)
+	} else {
+		c.evictNode(c.window, candidate)
+	}
+}
+
+// segmentOf 返回 key 所在的分段，不存在时返回 nil
+func (c *TinyLFU[K, V]) segmentOf(key K) *LRU[K, V] {
+	for _, l := range [...]*LRU[K, V]{c.window, c.probation, c.protected} {
+		if l.Contains(key) {
+			return l
+		}
+	}
+	return nil
+}
+
+// move 将 from 中的节点移动到 to 的队首，节点被复用，不会分配内存
+func (c *TinyLFU[K, V]) move(from, to *LRU[K, V], n *node[K, V]) {
+	from.ll.remove(n)
+	delete(from.cache, n.key)
+	from.nbytes -= n.size
+	to.ll.pushFront(n)
+	to.cache[n.key] = n
+	to.nbytes += n.size
+}
+
+// evictNode 从 l 中淘汰一个节点
+func (c *TinyLFU[K, V]) evictNode(l *LRU[K, V], n *node[K, V]) {
+	l.removeNode(n)
+	if c.onEnvicted != nil {
+		c.onEnvicted(n.key, n.value)
+	}
+}
+
+// Remove 移除 key 对应的条目
+func (c *TinyLFU[K, V]) Remove(key K) {
+	if l := c.segmentOf(key); l != nil {
+		c.evictNode(l, l.cache[key])
+	}
+}
+
+// RemoveExpired 移除所有已经过期的条目
+func (c *TinyLFU[K, V]) RemoveExpired() int {
+	now, count := time.Now(), 0
+	for _, l := range [...]*LRU[K, V]{c.window, c.probation, c.protected} {
+		for n := l.ll.back(); n != nil; {
+			prev := l.ll.prevOf(n)
+			if expired(n.expire, now) {
+				c.evictNode(l, n)
+				count++
+			}
+			n = prev
+		}
+	}
+	return count
+}
+
+// Evict 依次从 probation、protected 和窗口中淘汰最久未访问的条目
+func (c *TinyLFU[K, V]) Evict() (key K, value V, ok bool) {
+	for _, l := range [...]*LRU[K, V]{c.probation, c.protected, c.window} {
+		if n := l.ll.back(); n != nil {
+			c.evictNode(l, n)
+			return n.key, n.value, true
+		}
+	}
+	return
+}
+
+// Len 返回所有分段的条目数
+func (c *TinyLFU[K, V]) Len() int {
+	return c.window.Len() + c.probation.Len() + c.protected.Len()
+}
+
+// Bytes 返回所有分段占用的字节数
+func (c *TinyLFU[K, V]) Bytes() int64 {
+	return c.window.Bytes() + c.probation.Bytes() + c.protected.Bytes()
+}
+
+var _ TypedCache[string, int] = (*TinyLFU[string, int])(nil)
@@ -2,6 +2,7 @@ package purgekit
 
 import (
 	"testing"
+	"time"
 )
 
 func TestLFUAdd(t *testing.T) {
@@ -51,3 +52,98 @@ func TestFreq(t *testing.T) {
 		t.Fatalf("minFreq should be 1, but got: %v", lfu.minFreq)
 	}
 }
+
+func TestLFUExpire(t *testing.T) {
+	lfu := NewLFUCache(0, nil)
+	lfu.AddWithExpire("expired", 1, time.Now().Add(-time.Second))
+	lfu.Add("key", 2)
+	lfu.Get("key")
+	if _, ok := lfu.Get("expired"); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+	if lfu.minFreq != 1 {
+		t.Fatalf("minFreq should be 1 after removing expired entry, but got: %v", lfu.minFreq)
+	}
+	lfu.AddWithExpire("expired", 1, time.Now().Add(-time.Second))
+	if n := lfu.RemoveExpired(); n != 1 || lfu.Len() != 1 {
+		t.Fatalf("RemoveExpired should remove 1 entry, but got %v with %v kept", n, lfu.Len())
+	}
+}
+
+func TestRemoveLeastUsedAfterRemove(t *testing.T) {
+	lfu := NewLFUCache(2, nil)
+	lfu.Add("key1", 1)
+	lfu.Add("key2", 2)
+	lfu.Get("key2")
+	lfu.Remove("key1")
+	lfu.Add("key3", 3)
+	lfu.Add("key4", 4)
+	if _, ok := lfu.Get("key3"); ok {
+		t.Fatal("key3 should have been removed")
+	}
+	if _, ok := lfu.Get("key2"); !ok {
+		t.Fatal("key2 should be kept")
+	}
+}
+
+func TestLFUMaxBytes(t *testing.T) {
+	sizeFunc := func(key Key, value interface{}) int64 {
+		return int64(len(key.(string)) + len(value.(string)))
+	}
+	lfu, err := New("lfu", Options{MaxBytes: 10, SizeFunc: sizeFunc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lfu.Add("k1", "v1")
+	lfu.Add("k2", "v2")
+	lfu.Get("k1")
+	lfu.Add("k3", "v3")
+	if lfu.Len() != 2 || lfu.Bytes() != 8 {
+		t.Fatalf("lfu should evict until under 10 bytes, but got %v entries, %v bytes", lfu.Len(), lfu.Bytes())
+	}
+	if _, ok := lfu.Get("k2"); ok {
+		t.Fatal("k2 should have been evicted")
+	}
+}
+
+func TestLFUOnEnvicted(t *testing.T) {
+	envictedKeys := make([]Key, 0)
+	lfu := NewLFUCache(1, func(key Key, value interface{}) {
+		envictedKeys = append(envictedKeys, key)
+	})
+	lfu.Add("key1", 1)
+	lfu.Add("key2", 2)
+	if len(envictedKeys) != 1 || envictedKeys[0] != Key("key1") {
+		t.Fatalf("key1 should be envicted, but got %v", envictedKeys)
+	}
+}
+
+func TestLFUFreqList(t *testing.T) {
+	lfu := NewLFUCache(2, nil)
+	lfu.Add("hot", 1)
+	for i := 0; i < 100000; i++ {
+		lfu.Get("hot")
+	}
+	if len(lfu.freqList) != 1 {
+		t.Fatalf("empty freq lists should be deleted, but got %v lists", len(lfu.freqList))
+	}
+
+	lfu.Add("cold", 2)
+	lfu.Remove("cold")
+	if lfu.minFreq != 100000 {
+		t.Fatalf("minFreq should be 100000 after removing cold, but got: %v", lfu.minFreq)
+	}
+	lfu.Add("key1", 1)
+	lfu.Add("key2", 2)
+	if _, ok := lfu.Get("hot"); !ok {
+		t.Fatal("hot should be kept")
+	}
+	if _, ok := lfu.Get("key1"); ok {
+		t.Fatal("key1 should have been removed")
+	}
+	lfu.Remove("key2")
+	lfu.Remove("hot")
+	if lfu.minFreq != -1 || len(lfu.freqList) != 0 {
+		t.Fatalf("empty cache should have no freq list, but got minFreq %v with %v lists", lfu.minFreq, len(lfu.freqList))
+	}
+}
@@ -3,6 +3,7 @@ package purgekit
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 type simpleStruct struct {
@@ -63,7 +64,7 @@ func TestOnEvictd(t *testing.T) {
 	}
 
 	lru := NewLRUCache(20)
-	lru.onEnvited = envictedFunc
+	lru.onEnvicted = envictedFunc
 	for i := 0; i < 22; i++ {
 		lru.Add(fmt.Sprintf("mykey%d", i), 1234)
 	}
@@ -77,3 +78,75 @@ func TestOnEvictd(t *testing.T) {
 		t.Fatalf("got %v in first envicted key; want mykey0", envictedKeys[1])
 	}
 }
+
+func TestExpire(t *testing.T) {
+	lru := NewLRUCache(0)
+	lru.AddWithExpire("expired", 1234, time.Now().Add(-time.Second))
+	lru.AddWithExpire("alive", 1234, time.Now().Add(time.Hour))
+	lru.Add("forever", 1234)
+	if _, ok := lru.Get("expired"); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+	if lru.Len() != 2 {
+		t.Fatalf("expired entry should be removed on Get, but got %v entries", lru.Len())
+	}
+	if _, ok := lru.Get("alive"); !ok {
+		t.Fatal("alive entry should be returned")
+	}
+}
+
+func TestRemoveExpired(t *testing.T) {
+	lru := NewLRUCache(0)
+	for i := 0; i < 10; i++ {
+		lru.AddWithExpire(i, i, time.Now().Add(-time.Second))
+	}
+	lru.Add("forever", 1234)
+	if n := lru.RemoveExpired(); n != 10 {
+		t.Fatalf("RemoveExpired should remove 10 entries, but got %v", n)
+	}
+	if lru.Len() != 1 {
+		t.Fatalf("lru should have 1 entry kept, but got %v", lru.Len())
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	sizeFunc := func(key Key, value interface{}) int64 {
+		return int64(len(key.(string)) + len(value.(string)))
+	}
+	lru, err := New("lru", Options{MaxBytes: 10, SizeFunc: sizeFunc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lru.Add("k1", "v1")
+	lru.Add("k2", "v2")
+	if lru.Bytes() != 8 {
+		t.Fatalf("lru should hold 8 bytes, but got %v", lru.Bytes())
+	}
+	lru.Add("k3", "v3")
+	if lru.Len() != 2 || lru.Bytes() != 8 {
+		t.Fatalf("lru should evict until under 10 bytes, but got %v entries, %v bytes", lru.Len(), lru.Bytes())
+	}
+	if _, ok := lru.Get("k1"); ok {
+		t.Fatal("k1 should have been evicted")
+	}
+	lru.Add("k2", "a long value")
+	if lru.Len() != 0 || lru.Bytes() != 0 {
+		t.Fatalf("an oversized entry should not be kept, but got %v entries, %v bytes", lru.Len(), lru.Bytes())
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		c, err := NewCache(policy, 2, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", policy, err)
+		}
+		c.Add("key", 1234)
+		if value, ok := c.Get("key"); !ok || value != 1234 {
+			t.Fatalf("%s: want 1234, but got %v", policy, value)
+		}
+	}
+	if _, err := NewCache("unknown", 2, nil); err == nil {
+		t.Fatal("unknown policy should return an error")
+	}
+}
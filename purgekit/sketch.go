@@ -0,0 +1,201 @@
+package purgekit
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+const (
+	// sketchDepth 是 count-min sketch 的行数
+	sketchDepth = 4
+	// sketchMaxCount 是计数器的最大值，与 4 位计数器相同
+	sketchMaxCount = 15
+	// sketchMinWidth 是 count-min sketch 每行的最少计数器数量
+	sketchMinWidth = 16
+	// sketchWidthFactor 是每行计数器数量与缓存容量的比例，计数器越多冲突越少
+	sketchWidthFactor = 4
+)
+
+// cmSketch 是 count-min sketch，用于估计 key 最近的访问频率
+// 每个 key 在每一行对应一个计数器，估计值取各行中的最小值
+// 计数的次数达到 sampleSize 后所有计数器减半，使旧的访问记录逐渐失效
+type cmSketch struct {
+	rows       [sketchDepth][]uint8
+	mask       uint64
+	additions  int // additions 是上次减半之后计数的次数
+	sampleSize int // sampleSize 是触发减半的计数次数
+}
+
+// newCMSketch 根据缓存容量创建 cmSketch，每行的计数器数量是不小于 sketchWidthFactor 倍容量的 2 的幂
+// 每计数 10 倍容量的次数减半一次
+func newCMSketch(capacity int) *cmSketch {
+	if capacity < sketchMinWidth {
+		capacity = sketchMinWidth
+	}
+	width := sketchMinWidth
+	for width < capacity*sketchWidthFactor {
+		width <<= 1
+	}
+	s := &cmSketch{mask: uint64(width - 1), sampleSize: 10 * capacity}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index 返回 hash 在第 i 行中对应的计数器下标
+func (s *cmSketch) index(hash uint64, i int) uint64 {
+	return mixHash(hash+uint64(i)*0x9e3779b97f4a7c15) & s.mask
+}
+
+// increment 记录一次访问
+func (s *cmSketch) increment(hash uint64) {
+	added := false
+	for i := range s.rows {
+		if idx := s.index(hash, i); s.rows[i][idx] < sketchMaxCount {
+			s.rows[i][idx]++
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+	if s.additions++; s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// estimate 返回访问频率的估计值
+func (s *cmSketch) estimate(hash uint64) uint8 {
+	min := uint8(sketchMaxCount)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(hash, i)]; v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// reset 将所有计数器减半
+func (s *cmSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+// hashKey 计算接口类型的 key 的哈希值，key 已经是接口类型，通过反射计算时不需要分配内存
+// 相等的 key 哈希值相同
+func hashKey(key Key) uint64 {
+	return hashValue(reflect.ValueOf(key))
+}
+
+// hashValue 按值计算 v 的哈希值，v 必须是可比较的类型
+func hashValue(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.String:
+		return hashString(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			return mixHash(1)
+		}
+		return mixHash(0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mixHash(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mixHash(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return combineHash(hashFloat(real(c)), hashFloat(imag(c)))
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return mixHash(uint64(v.Pointer()))
+	case reflect.Interface:
+		return hashValue(v.Elem())
+	case reflect.Array:
+		h := uint64(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			h = combineHash(h, hashValue(v.Index(i)))
+		}
+		return h
+	case reflect.Struct:
+		h := uint64(v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			h = combineHash(h, hashValue(v.Field(i)))
+		}
+		return h
+	default:
+		panic(fmt.Sprintf("purgekit: key of type %s is not comparable", v.Type()))
+	}
+}
+
+// keyHasher 返回 K 的哈希函数，K 的底层类型是字符串、布尔值、数字或者接口时不需要分配内存
+// 其他类型返回 false，需要通过 Config.Hash 指定
+func keyHasher[K comparable]() (func(K) uint64, bool) {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	switch t.Kind() {
+	case reflect.Interface:
+		return func(key K) uint64 { return hashKey(any(key)) }, true
+	case reflect.String:
+		return func(key K) uint64 { return hashString(*(*string)(unsafe.Pointer(&key))) }, true
+	case reflect.Float32:
+		return func(key K) uint64 { return hashFloat(float64(*(*float32)(unsafe.Pointer(&key)))) }, true
+	case reflect.Float64:
+		return func(key K) uint64 { return hashFloat(*(*float64)(unsafe.Pointer(&key))) }, true
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// 整数按照内存中的位计算，不同大小的类型分别读取
+		switch t.Size() {
+		case 1:
+			return func(key K) uint64 { return mixHash(uint64(*(*uint8)(unsafe.Pointer(&key)))) }, true
+		case 2:
+			return func(key K) uint64 { return mixHash(uint64(*(*uint16)(unsafe.Pointer(&key)))) }, true
+		case 4:
+			return func(key K) uint64 { return mixHash(uint64(*(*uint32)(unsafe.Pointer(&key)))) }, true
+		default:
+			return func(key K) uint64 { return mixHash(*(*uint64)(unsafe.Pointer(&key))) }, true
+		}
+	default:
+		return nil, false
+	}
+}
+
+// hashFloat 计算浮点数的哈希值，0 和 -0 相等，哈希值也相同
+func hashFloat(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+	return mixHash(math.Float64bits(f))
+}
+
+// combineHash 合并两个哈希值
+func combineHash(h, v uint64) uint64 {
+	return mixHash(h*31 + v)
+}
+
+// hashString 是 FNV-1a 哈希
+func hashString(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return mixHash(h)
+}
+
+// mixHash 是 splitmix64 的最后一步，使每一位都充分混合
+func mixHash(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
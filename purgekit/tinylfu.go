@@ -0,0 +1,15 @@
+package purgekit
+
+// TinyLFUCache 是键和值为任意类型的 W-TinyLFU 缓存，所有的操作由 TinyLFU[Key, interface{}] 实现
+type TinyLFUCache struct {
+	TinyLFU[Key, interface{}]
+}
+
+// NewTinyLFUCache 返回一个 TinyLFUCache 实例
+func NewTinyLFUCache(maxEntries int, onEnvicted func(key Key, value interface{})) *TinyLFUCache {
+	return &TinyLFUCache{TinyLFU: *NewTinyLFU(Config[Key, interface{}]{MaxEntries: maxEntries, OnEnvicted: onEnvicted})}
+}
+
+func (c *TinyLFUCache) RegisterOnEnvicted(onfunc OnEnvictedFunc) {
+	c.onEnvicted = onfunc
+}
@@ -0,0 +1,209 @@
+package purgekit
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestTinyLFUScanResistance(t *testing.T) {
+	c := NewTinyLFUCache(100, nil)
+	// 热点 key 被多次访问
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 50; j++ {
+			key := fmt.Sprintf("hot%d", j)
+			if _, ok := c.Get(key); !ok {
+				c.Add(key, j)
+			}
+		}
+	}
+	// 只访问一次的 key 不应该替换掉热点 key
+	for i := 0; i < 1000; i++ {
+		c.Add(fmt.Sprintf("scan%d", i), i)
+	}
+	for j := 0; j < 50; j++ {
+		if _, ok := c.Get(fmt.Sprintf("hot%d", j)); !ok {
+			t.Fatalf("hot%d should survive the scan", j)
+		}
+	}
+	if c.Len() != 100 {
+		t.Fatalf("want 100 entries, but got %d", c.Len())
+	}
+}
+
+func TestTinyLFUExpireAndRemove(t *testing.T) {
+	var evicted []Key
+	c := NewTinyLFUCache(10, func(key Key, value interface{}) {
+		evicted = append(evicted, key)
+	})
+	c.AddWithExpire("expired", 1, time.Now().Add(-time.Second))
+	c.AddWithExpire("alive", 2, time.Now().Add(time.Hour))
+	c.Add("removed", 3)
+	if _, ok := c.Get("expired"); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+	c.Remove("removed")
+	if v, ok := c.Get("alive"); !ok || v != 2 {
+		t.Fatalf("want alive=2, but got %v, %v", v, ok)
+	}
+	if c.Len() != 1 || len(evicted) != 2 {
+		t.Fatalf("want 1 entry and 2 evicted, but got %d entries, evicted %v", c.Len(), evicted)
+	}
+
+	c.AddWithExpire("expired", 1, time.Now().Add(-time.Second))
+	if n := c.RemoveExpired(); n != 1 {
+		t.Fatalf("RemoveExpired should remove 1 entry, but got %d", n)
+	}
+}
+
+func TestSketchAging(t *testing.T) {
+	s := newCMSketch(16)
+	h := hashKey("key")
+	for i := 0; i < 10; i++ {
+		s.increment(h)
+	}
+	if n := s.estimate(h); n != 10 {
+		t.Fatalf("want estimate 10, but got %d", n)
+	}
+	// 计数次数达到 sampleSize 后所有计数器减半
+	for i := 0; ; i++ {
+		additions := s.additions
+		s.increment(hashKey(i))
+		if s.additions < additions {
+			break
+		}
+	}
+	if n := s.estimate(h); n >= 10 {
+		t.Fatalf("estimate should be halved after aging, but got %d", n)
+	}
+}
+
+type namedKey string
+
+type pointKey struct {
+	x, y int
+	name string
+}
+
+// TestHashKey 检查相等的 key 哈希值相同，并且计算时不分配内存
+func TestHashKey(t *testing.T) {
+	if hashKey(pointKey{1, 2, "a"}) != hashKey(pointKey{1, 2, "a"}) {
+		t.Fatal("equal struct keys should have the same hash")
+	}
+	if hashKey(pointKey{1, 2, "a"}) == hashKey(pointKey{2, 1, "a"}) {
+		t.Fatal("different struct keys should have different hashes")
+	}
+	if hashKey(0.0) != hashKey(math.Copysign(0, -1)) {
+		t.Fatal("0 and -0 should have the same hash")
+	}
+	var key Key = pointKey{1, 2, "a"}
+	if n := testing.AllocsPerRun(100, func() { hashKey(key) }); n != 0 {
+		t.Fatalf("hashKey should not allocate, but got %v allocs", n)
+	}
+
+	hash, ok := keyHasher[namedKey]()
+	if !ok || hash("Tom") != hashString("Tom") {
+		t.Fatal("named string keys should be hashed as strings")
+	}
+	if n := testing.AllocsPerRun(100, func() { hash("Tom") }); n != 0 {
+		t.Fatalf("keyHasher should not allocate, but got %v allocs", n)
+	}
+	if _, ok := keyHasher[pointKey](); ok {
+		t.Fatal("struct keys should require Config.Hash")
+	}
+}
+
+func TestTinyLFUConfig(t *testing.T) {
+	if _, err := New(PolicyTinyLFU, Options{MaxBytes: 10}); err == nil {
+		t.Fatal("tinylfu without MaxEntries should be rejected")
+	}
+	if _, err := NewTyped(PolicyTinyLFU, Config[pointKey, int]{MaxEntries: 10}); err == nil {
+		t.Fatal("tinylfu with struct keys should require Config.Hash")
+	}
+	c, err := NewTyped(PolicyTinyLFU, Config[pointKey, int]{
+		MaxEntries: 10,
+		Hash:       func(key pointKey) uint64 { return hashString(key.name) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Add(pointKey{1, 2, "a"}, 1)
+	if v, ok := c.Get(pointKey{1, 2, "a"}); !ok || v != 1 {
+		t.Fatalf("want 1, but got %v, %v", v, ok)
+	}
+}
+
+// hitRatio 使用 trace 回放访问，未命中时写入缓存，返回命中率
+func hitRatio(policy string, capacity int, trace []int) float64 {
+	c, _ := NewTyped(policy, Config[int, int]{MaxEntries: capacity})
+	hits := 0
+	for _, key := range trace {
+		if _, ok := c.Get(key); ok {
+			hits++
+		} else {
+			c.Add(key, key)
+		}
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+// zipfTrace 生成服从 Zipf 分布的访问序列
+// scan 大于 0 时每 10000 次访问插入一次 scan 个只访问一次的顺序扫描；
+// shift 大于 0 时每 shift 次访问更换一次热点 key
+func zipfTrace(n, scan, shift int) []int {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, 100000)
+	trace := make([]int, 0, n)
+	next, offset := 1000000, 0
+	for len(trace) < n {
+		if shift > 0 && len(trace) > 0 && len(trace)%shift == 0 {
+			offset += 100000
+		}
+		trace = append(trace, offset+int(zipf.Uint64()))
+		if scan > 0 && len(trace)%10000 == 0 {
+			for i := 0; i < scan; i++ {
+				trace = append(trace, next)
+				next++
+			}
+		}
+	}
+	return trace
+}
+
+// TestHitRatio 比较各个淘汰策略的命中率
+// tinylfu 应当明显好于在该访问序列下表现差的策略，并且与最好的策略相差不大
+func TestHitRatio(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		trace []int
+		beats []string // beats 是 tinylfu 应当超过的策略
+		slack float64  // slack 是 tinylfu 与最好的策略之间允许的差距
+	}{
+		{"zipf", zipfTrace(100000, 0, 0), []string{PolicyLRU}, 0.01},
+		// 顺序扫描会冲掉 LRU 中的热点 key
+		{"zipf-scan", zipfTrace(100000, 2000, 0), []string{PolicyLRU}, 0.01},
+		// 热点变化后 LFU 中旧的热点 key 无法被淘汰，tinylfu 的 sketch 会定期减半
+		{"zipf-shift", zipfTrace(100000, 0, 20000), []string{PolicyLFU}, 0.05},
+	} {
+		ratios := make(map[string]float64)
+		best := 0.0
+		for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+			ratios[policy] = hitRatio(policy, 1000, tc.trace)
+			if ratios[policy] > best {
+				best = ratios[policy]
+			}
+		}
+		t.Logf("%s: %v", tc.name, ratios)
+		tinylfu := ratios[PolicyTinyLFU]
+		for _, policy := range tc.beats {
+			if tinylfu <= ratios[policy]+0.01 {
+				t.Fatalf("%s: tinylfu hit ratio %.4f should be higher than %s %.4f", tc.name, tinylfu, policy, ratios[policy])
+			}
+		}
+		if tinylfu < best-tc.slack {
+			t.Fatalf("%s: tinylfu hit ratio %.4f is too far from the best %.4f", tc.name, tinylfu, best)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/naming/endpoints"
+)
+
+const (
+	defaultEtcdEndpoint    = "localhost:2379"
+	defaultEtcdDialTimeout = 5 * time.Second
+	defaultLeaseTTL        = 5 * time.Second
+	// reregisterInterval 是续约中断后重新注册的重试间隔
+	reregisterInterval = time.Second
+
+	weightKey = "weight" // weightKey 是节点权重在 Endpoint.Metadata 中的键
+)
+
+// EtcdConfig 是 Etcd 的配置，零值表示连接本机的 etcd
+type EtcdConfig struct {
+	Endpoints   []string      // Endpoints 为空时使用 localhost:2379
+	DialTimeout time.Duration // DialTimeout 为 0 时使用 5s
+	LeaseTTL    time.Duration // LeaseTTL 是注册记录的租约时长，为 0 时使用 5s
+	Username    string
+	Password    string
+	TLS         *tls.Config // TLS 不为 nil 时使用 TLS 连接 etcd
+}
+
+// Etcd 是基于 etcd 的 Registry 实现
+// 节点以租约的方式写入 etcd，进程退出后记录会在租约到期后自动删除
+type Etcd struct {
+	cli      *clientv3.Client
+	leaseTTL int64
+
+	mu     sync.Mutex
+	leases map[string]*lease // key 是 etcd 中的记录
+}
+
+// lease 是一次注册对应的租约
+type lease struct {
+	id     clientv3.LeaseID   // id 是当前的租约，重新注册后会变化，只能在持有 mu 时读写
+	cancel context.CancelFunc // cancel 停止续约和重新注册
+}
+
+// NewEtcd 根据 cfg 创建一个 Etcd 实例
+func NewEtcd(cfg EtcdConfig) (*Etcd, error) {
+	if len(cfg.Endpoints) == 0 {
+		cfg.Endpoints = []string{defaultEtcdEndpoint}
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = defaultEtcdDialTimeout
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = defaultLeaseTTL
+	}
+	if cfg.LeaseTTL < time.Second {
+		return nil, fmt.Errorf("lease ttl must be at least 1s, got %v", cfg.LeaseTTL)
+	}
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   cfg.Endpoints,
+		DialTimeout: cfg.DialTimeout,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+		TLS:         cfg.TLS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create etcd client failed: %v", err)
+	}
+	return &Etcd{
+		cli:      cli,
+		leaseTTL: int64(cfg.LeaseTTL / time.Second),
+		leases:   make(map[string]*lease),
+	}, nil
+}
+
+// Client 返回底层的 etcd 客户端，可以配合 EtcdDial 使用
+func (r *Etcd) Client() *clientv3.Client {
+	return r.cli
+}
+
+// Register 在租约模式下将 member 写入 etcd，并在后台续约
+// 续约中断后（例如租约已经过期，或者与 etcd 的连接长时间断开）会重新创建租约并写入记录，直到 Deregister
+func (r *Etcd) Register(ctx context.Context, service string, member Member) error {
+	key := service + "/" + member.Addr
+	kctx, cancel := context.WithCancel(context.Background())
+	id, ch, err := r.put(ctx, kctx, service, member)
+	if err != nil {
+		cancel()
+		return err
+	}
+	l := &lease{id: id, cancel: cancel}
+	r.mu.Lock()
+	old, ok := r.leases[key]
+	var oldID clientv3.LeaseID
+	if ok {
+		oldID = old.id
+	}
+	r.leases[key] = l
+	r.mu.Unlock()
+	if ok {
+		// 重复注册时释放旧的租约
+		old.cancel()
+		r.cli.Revoke(context.Background(), oldID)
+	}
+	go r.keepAlive(kctx, service, member, l, ch)
+	log.Printf("%s register service ok\n", member.Addr)
+	return nil
+}
+
+// put 创建租约，将 member 写入 etcd，并使用 kctx 续约
+func (r *Etcd) put(ctx, kctx context.Context, service string, member Member) (clientv3.LeaseID, <-chan *clientv3.LeaseKeepAliveResponse, error) {
+	resp, err := r.cli.Grant(ctx, r.leaseTTL)
+	if err != nil {
+		return 0, nil, fmt.Errorf("create lease failed: %v", err)
+	}
+	em, err := endpoints.NewManager(r.cli, service)
+	if err != nil {
+		r.cli.Revoke(context.Background(), resp.ID)
+		return 0, nil, err
+	}
+	endpoint := endpoints.Endpoint{Addr: member.Addr}
+	if member.Weight > 0 {
+		endpoint.Metadata = map[string]interface{}{weightKey: member.Weight}
+	}
+	err = em.AddEndpoint(ctx, service+"/"+member.Addr, endpoint, clientv3.WithLease(resp.ID))
+	if err != nil {
+		r.cli.Revoke(context.Background(), resp.ID)
+		return 0, nil, fmt.Errorf("add etcd record failed: %v", err)
+	}
+	ch, err := r.cli.KeepAlive(kctx, resp.ID)
+	if err != nil {
+		r.cli.Revoke(context.Background(), resp.ID)
+		return 0, nil, fmt.Errorf("set keepalive failed: %v", err)
+	}
+	return resp.ID, ch, nil
+}
+
+// keepAlive 接收续约的响应，续约中断后每隔 reregisterInterval 尝试重新注册，直到 ctx 被取消
+func (r *Etcd) keepAlive(ctx context.Context, service string, member Member, l *lease, ch <-chan *clientv3.LeaseKeepAliveResponse) {
+	for {
+		for range ch {
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("%s keep alive channel closed, register again", member.Addr)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(reregisterInterval):
+			}
+			pctx, cancel := context.WithTimeout(ctx, defaultEtcdDialTimeout)
+			id, next, err := r.put(pctx, ctx, service, member)
+			cancel()
+			if err != nil {
+				log.Printf("%s register again failed: %v", member.Addr, err)
+				continue
+			}
+			r.mu.Lock()
+			l.id = id
+			r.mu.Unlock()
+			if ctx.Err() != nil {
+				// 重新注册的同时被注销，Deregister 可能撤销的是旧的租约
+				r.cli.Revoke(context.Background(), id)
+				return
+			}
+			log.Printf("%s register service again ok\n", member.Addr)
+			ch = next
+			break
+		}
+	}
+}
+
+// Deregister 停止续约并删除 etcd 中的记录
+func (r *Etcd) Deregister(ctx context.Context, service string, addr string) error {
+	key := service + "/" + addr
+	r.mu.Lock()
+	l, ok := r.leases[key]
+	var id clientv3.LeaseID
+	if ok {
+		id = l.id
+	}
+	delete(r.leases, key)
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	l.cancel()
+	if _, err := r.cli.Revoke(ctx, id); err != nil {
+		return fmt.Errorf("revoke lease failed: %v", err)
+	}
+	return nil
+}
+
+// Watch 订阅 etcd 中 service 前缀下的节点变化
+func (r *Etcd) Watch(ctx context.Context, service string) (<-chan []Event, error) {
+	em, err := endpoints.NewManager(r.cli, service)
+	if err != nil {
+		return nil, err
+	}
+	updates, err := em.NewWatchChannel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("watch %s failed: %v", service, err)
+	}
+	ch := make(chan []Event)
+	go func() {
+		defer close(ch)
+		for ups := range updates {
+			events := make([]Event, 0, len(ups))
+			for _, up := range ups {
+				// 删除事件中没有 Endpoint，从 key 中解析地址
+				m := Member{Addr: strings.TrimPrefix(up.Key, service+"/")}
+				switch up.Op {
+				case endpoints.Add:
+					m.Weight = weightOf(up.Endpoint.Metadata)
+					events = append(events, Event{Op: Add, Member: m})
+				case endpoints.Delete:
+					events = append(events, Event{Op: Delete, Member: m})
+				}
+			}
+			select {
+			case ch <- events:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// weightOf 从 Endpoint.Metadata 中解析节点的权重，没有权重时返回 0
+// Metadata 在 etcd 中以 JSON 保存，读取时数字会被解析为 float64
+func weightOf(metadata interface{}) int {
+	md, ok := metadata.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	switch w := md[weightKey].(type) {
+	case float64:
+		return int(w)
+	case int:
+		return w
+	}
+	return 0
+}
+
+// Close 撤销所有通过它注册的记录并关闭 etcd 客户端
+func (r *Etcd) Close() error {
+	r.mu.Lock()
+	leases := r.leases
+	r.leases = make(map[string]*lease)
+	ids := make([]clientv3.LeaseID, 0, len(leases))
+	for _, l := range leases {
+		ids = append(ids, l.id)
+	}
+	r.mu.Unlock()
+	for _, l := range leases {
+		l.cancel()
+	}
+	for _, id := range ids {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdDialTimeout)
+		r.cli.Revoke(ctx, id)
+		cancel()
+	}
+	return r.cli.Close()
+}
+
+var _ Registry = (*Etcd)(nil)
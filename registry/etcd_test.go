@@ -0,0 +1,20 @@
+package registry
+
+import "testing"
+
+func TestWeightOf(t *testing.T) {
+	tests := []struct {
+		metadata interface{}
+		want     int
+	}{
+		{nil, 0},
+		{map[string]interface{}{"weight": float64(4)}, 4},
+		{map[string]interface{}{"weight": 2}, 2},
+		{map[string]interface{}{"weight": "4"}, 0},
+	}
+	for _, tt := range tests {
+		if got := weightOf(tt.metadata); got != tt.want {
+			t.Errorf("weightOf(%v) = %d; expect %d", tt.metadata, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package registry
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory 是进程内的 Registry 实现，用于测试或者单进程内的多个节点
+type Memory struct {
+	mu       sync.Mutex
+	services map[string]map[string]Member
+	watchers map[string]map[*watcher]struct{}
+}
+
+// NewMemory 返回一个空的 Memory 实例
+func NewMemory() *Memory {
+	return &Memory{
+		services: make(map[string]map[string]Member),
+		watchers: make(map[string]map[*watcher]struct{}),
+	}
+}
+
+// Register 将 member 加入 service，并通知所有订阅者
+func (r *Memory) Register(ctx context.Context, service string, member Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	members := r.services[service]
+	if members == nil {
+		members = make(map[string]Member)
+		r.services[service] = members
+	}
+	members[member.Addr] = member
+	r.notify(service, []Event{{Op: Add, Member: member}})
+	return nil
+}
+
+// Deregister 将 addr 从 service 中移除，并通知所有订阅者
+func (r *Memory) Deregister(ctx context.Context, service string, addr string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	member, ok := r.services[service][addr]
+	if !ok {
+		return nil
+	}
+	delete(r.services[service], addr)
+	r.notify(service, []Event{{Op: Delete, Member: member}})
+	return nil
+}
+
+// Watch 订阅 service 的节点变化
+func (r *Memory) Watch(ctx context.Context, service string) (<-chan []Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w := newWatcher(ctx)
+	if r.watchers[service] == nil {
+		r.watchers[service] = make(map[*watcher]struct{})
+	}
+	r.watchers[service][w] = struct{}{}
+	if events := diff(nil, r.services[service]); len(events) > 0 {
+		w.push(events)
+	}
+	go func() {
+		<-ctx.Done()
+		r.mu.Lock()
+		delete(r.watchers[service], w)
+		r.mu.Unlock()
+	}()
+	return w.ch, nil
+}
+
+// Close 移除所有节点
+func (r *Memory) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for service, members := range r.services {
+		r.notify(service, diff(members, nil))
+	}
+	r.services = make(map[string]map[string]Member)
+	return nil
+}
+
+// notify 将 events 发送给 service 的所有订阅者，调用时需要持有锁
+func (r *Memory) notify(service string, events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	for w := range r.watchers[service] {
+		w.push(events)
+	}
+}
+
+// watcher 将事件按顺序发送给订阅者，发送不会阻塞通知方
+type watcher struct {
+	ch     chan []Event
+	mu     sync.Mutex
+	queue  [][]Event
+	signal chan struct{}
+}
+
+func newWatcher(ctx context.Context) *watcher {
+	w := &watcher{
+		ch:     make(chan []Event),
+		signal: make(chan struct{}, 1),
+	}
+	go w.run(ctx)
+	return w
+}
+
+// push 将事件加入队列
+func (w *watcher) push(events []Event) {
+	w.mu.Lock()
+	w.queue = append(w.queue, events)
+	w.mu.Unlock()
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// run 将队列中的事件发送到 ch，直到 ctx 被取消
+func (w *watcher) run(ctx context.Context) {
+	defer close(w.ch)
+	for {
+		w.mu.Lock()
+		var events []Event
+		if len(w.queue) > 0 {
+			events, w.queue = w.queue[0], w.queue[1:]
+		}
+		w.mu.Unlock()
+		if events == nil {
+			select {
+			case <-w.signal:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case w.ch <- events:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+var _ Registry = (*Memory)(nil)
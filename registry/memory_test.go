@@ -0,0 +1,47 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// recv 从 ch 中读取一批事件
+func recv(t *testing.T, ch <-chan []Event) []Event {
+	t.Helper()
+	select {
+	case events := <-ch:
+		return events
+	case <-time.After(time.Second):
+		t.Fatal("wait events timeout")
+	}
+	return nil
+}
+
+func TestMemory(t *testing.T) {
+	r := NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Register(ctx, "pcache", Member{Addr: "127.0.0.1:6324"})
+
+	ch, err := r.Watch(ctx, "pcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := recv(t, ch); len(events) != 1 || events[0] != (Event{Op: Add, Member: Member{Addr: "127.0.0.1:6324"}}) {
+		t.Fatalf("first events should contain current members, but got %v", events)
+	}
+
+	r.Register(ctx, "other", Member{Addr: "127.0.0.1:7000"})
+	r.Register(ctx, "pcache", Member{Addr: "127.0.0.1:6325"})
+	r.Deregister(ctx, "pcache", "127.0.0.1:6324")
+	if events := recv(t, ch); events[0] != (Event{Op: Add, Member: Member{Addr: "127.0.0.1:6325"}}) {
+		t.Fatalf("want add 127.0.0.1:6325, but got %v", events)
+	}
+	if events := recv(t, ch); events[0] != (Event{Op: Delete, Member: Member{Addr: "127.0.0.1:6324"}}) {
+		t.Fatalf("want delete 127.0.0.1:6324, but got %v", events)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,52 @@
+package registry
+
+import "context"
+
+// Member 是服务中的一个节点
+type Member struct {
+	Addr   string // Addr 是节点的地址 ip:port
+	Weight int    // Weight 是节点的权重，决定它在哈希环上的虚拟节点数，0 表示默认权重
+}
+
+// Op 表示节点变化的类型
+type Op uint8
+
+const (
+	Add    Op = iota // Add 表示节点加入
+	Delete           // Delete 表示节点离开
+)
+
+// Event 描述服务中一个节点的变化
+type Event struct {
+	Op     Op
+	Member Member
+}
+
+// Registry 定义了服务注册与发现的能力
+type Registry interface {
+	// Register 将 member 注册到 service 中，直到调用 Deregister 或 Close
+	Register(ctx context.Context, service string, member Member) error
+	// Deregister 将 addr 从 service 中移除
+	Deregister(ctx context.Context, service string, addr string) error
+	// Watch 订阅 service 的节点变化，第一批事件包含当前所有的节点
+	// ctx 取消后返回的 channel 会被关闭
+	Watch(ctx context.Context, service string) (<-chan []Event, error)
+	// Close 释放 Registry 持有的资源，通过它注册的节点会被移除
+	Close() error
+}
+
+// diff 比较新旧两组节点，返回从 old 变为 cur 所需的事件
+func diff(old, cur map[string]Member) []Event {
+	var events []Event
+	for addr, m := range old {
+		if _, ok := cur[addr]; !ok {
+			events = append(events, Event{Op: Delete, Member: m})
+		}
+	}
+	for addr, m := range cur {
+		if o, ok := old[addr]; !ok || o != m {
+			events = append(events, Event{Op: Add, Member: m})
+		}
+	}
+	return events
+}
@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultReloadInterval 是 Static 默认检查文件变化的间隔
+const defaultReloadInterval = 10 * time.Second
+
+// Static 从文件中读取节点列表，适用于没有注册中心的部署
+// 文件中每行是一个节点地址，地址后可以跟一个空格分隔的权重，空行和 # 开头的行会被忽略
+// 文件对所有 service 生效，Register 和 Deregister 不会修改文件
+type Static struct {
+	path     string
+	interval time.Duration
+}
+
+// NewStatic 返回读取 path 的 Static 实例，每隔 interval 重新读取一次文件
+// interval 为 0 时使用 defaultReloadInterval
+func NewStatic(path string, interval time.Duration) (*Static, error) {
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	if _, err := readMembers(path); err != nil {
+		return nil, err
+	}
+	return &Static{path: path, interval: interval}, nil
+}
+
+// Register 不做任何事，节点由文件决定
+func (r *Static) Register(ctx context.Context, service string, member Member) error {
+	return nil
+}
+
+// Deregister 不做任何事，节点由文件决定
+func (r *Static) Deregister(ctx context.Context, service string, addr string) error {
+	return nil
+}
+
+// Watch 返回文件中的节点，并在文件内容变化时发送增量事件
+func (r *Static) Watch(ctx context.Context, service string) (<-chan []Event, error) {
+	members, err := readMembers(r.path)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan []Event)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		events, old := diff(nil, members), map[string]Member(nil)
+		for {
+			if len(events) > 0 {
+				select {
+				case ch <- events:
+				case <-ctx.Done():
+					return
+				}
+				old = members
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			cur, err := readMembers(r.path)
+			if err != nil {
+				log.Printf("reload %s failed: %v", r.path, err)
+				events = nil
+				continue
+			}
+			members, events = cur, diff(old, cur)
+		}
+	}()
+	return ch, nil
+}
+
+// Close 不做任何事
+func (r *Static) Close() error {
+	return nil
+}
+
+// readMembers 读取文件中的节点列表
+func readMembers(path string) (map[string]Member, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open peers file failed: %v", err)
+	}
+	defer f.Close()
+	members := make(map[string]Member)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		m := Member{Addr: fields[0]}
+		if len(fields) > 1 {
+			weight, err := strconv.Atoi(fields[1])
+			if err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid weight of %s: %q", m.Addr, fields[1])
+			}
+			m.Weight = weight
+		}
+		members[m.Addr] = m
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read peers file failed: %v", err)
+	}
+	return members, nil
+}
+
+var _ Registry = (*Static)(nil)
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers")
+	if err := os.WriteFile(path, []byte("# peers\n127.0.0.1:6324\n\n127.0.0.1:6325\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewStatic(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := r.Watch(ctx, "pcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := recv(t, ch); len(events) != 2 {
+		t.Fatalf("want 2 members, but got %v", events)
+	}
+
+	if err := os.WriteFile(path, []byte("127.0.0.1:6324\n127.0.0.1:6326 4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[Event]bool)
+	for _, e := range recv(t, ch) {
+		got[e] = true
+	}
+	if len(got) != 2 || !got[Event{Op: Delete, Member: Member{Addr: "127.0.0.1:6325"}}] || !got[Event{Op: Add, Member: Member{Addr: "127.0.0.1:6326", Weight: 4}}] {
+		t.Fatalf("want delete 6325 and add 6326, but got %v", got)
+	}
+}
+
+func TestStaticMissingFile(t *testing.T) {
+	if _, err := NewStatic(filepath.Join(t.TempDir(), "peers"), 0); err == nil {
+		t.Fatal("missing peers file should return an error")
+	}
+}
+
+func TestStaticInvalidWeight(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers")
+	if err := os.WriteFile(path, []byte("127.0.0.1:6324 big\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStatic(path, 0); err == nil {
+		t.Fatal("invalid weight should return an error")
+	}
+}
@@ -5,60 +5,156 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"pcache/consistenthash"
 	pb "pcache/pcachepb"
+	"pcache/registry"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 const (
 	defaultAddr    = "127.0.0.1:6324"
 	defaultRepicas = 50
+	serviceName    = "pcache" // serviceName 是节点在注册中心中注册的服务名
+
+	// registerTimeout 是向注册中心注册和注销节点的超时时间
+	registerTimeout = 5 * time.Second
 )
 
 type server struct {
 	pb.UnimplementedPcacheServer
 
-	addr           string // ip:port
-	status         bool   //true: running false: stop
-	stopSignal     chan error
-	mu             sync.Mutex
-	consistentHash *consistenthash.Map
-	clients        map[string]*client
+	addr          string // ip:port
+	status        bool   //true: running false: stop
+	mu            sync.Mutex
+	placement     atomic.Pointer[consistenthash.Placement] // placement 是选择节点使用的放置算法，可以在不持有锁的情况下读取
+	clients       map[string]*client                       // clients 只能在持有锁时修改
+	peers         atomic.Pointer[map[string]*client]       // peers 是 clients 的只读快照，供 Pick 无锁读取
+	dialOpts      []grpc.DialOption                        // dialOpts 是连接其他节点时额外使用的选项
+	metricsAddr   string                                   // metricsAddr 不为空时，Start 会在该地址提供 /metrics
+	metricsServer *http.Server                             // metricsServer 是正在运行的指标服务
+	reg           registry.Registry                        // reg 为 nil 时只使用 SetPeers 设置的节点
+	weight        int                                      // weight 是注册到注册中心时携带的权重
+	inflight      atomic.Int64                             // inflight 是正在处理的 Get 请求数，用于有界负载
+	cancelWatch   context.CancelFunc                       // cancelWatch 停止订阅节点变化
+	watchDone     chan struct{}                            // watchDone 在 watchPeers 返回后关闭
+	registered    chan struct{}                            // registered 在 Start 中的注册完成后关闭
+	grpcServer    *grpc.Server                             // grpcServer 是正在运行的 gRPC 服务
 }
 
 func NewServer(addr string) (*server, error) {
 	if addr == "" {
 		addr = defaultAddr
 	}
-	return &server{addr: addr}, nil
+	s := &server{
+		addr:    addr,
+		clients: make(map[string]*client),
+	}
+	var p consistenthash.Placement = consistenthash.New(defaultRepicas, nil)
+	s.placement.Store(&p)
+	s.publish()
+	return s, nil
 }
 
 // Get 是 rpc 服务要求的方法
 func (s *server) Get(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	defer serverGetLatency.since(time.Now())
+	s.inflight.Add(1)
+	defer s.inflight.Add(-1)
 	group, key := in.GetGroup(), in.GetKey()
 	repv := &pb.Response{}
 
 	log.Printf("[pcache server %s] Recv RPC Request - (%s)/(%s)", s.addr, group, key)
 	if key == "" {
-		return repv, fmt.Errorf("key required")
+		return repv, toStatus(ErrKeyRequired)
 	}
 	g := GetGroup(group)
 	if g == nil {
-		return repv, fmt.Errorf("group not found")
+		return repv, toStatus(ErrGroupNotFound)
 	}
-	view, err := g.Get(key)
+	view, err := g.GetContext(peerContext(ctx), key)
 	if err != nil {
-		return repv, err
+		return repv, toStatus(err)
 	}
 	repv.Value = view.ByteSlice()
+	if !view.Expire().IsZero() {
+		repv.Expire = view.Expire().UnixNano()
+	}
 	return repv, nil
 }
 
-// Start 启动服务器，todo: 并将其注册到 etcd 中
+// peerContext 在请求由其他节点转发而来时标记 ctx，使 Group 在本地处理而不是再次转发
+func peerContext(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(peerMetadataKey)) > 0 {
+		return withPeerRequest(ctx)
+	}
+	return ctx
+}
+
+// GetMulti 是 rpc 服务要求的方法，一次获取多个 key，每个 key 的错误单独返回
+func (s *server) GetMulti(ctx context.Context, in *pb.MultiRequest) (*pb.MultiResponse, error) {
+	defer serverGetLatency.since(time.Now())
+	s.inflight.Add(1)
+	defer s.inflight.Add(-1)
+	group, keys := in.GetGroup(), in.GetKeys()
+
+	log.Printf("[pcache server %s] Recv RPC Request - (%s)/(%d keys)", s.addr, group, len(keys))
+	g := GetGroup(group)
+	if g == nil {
+		return &pb.MultiResponse{}, toStatus(ErrGroupNotFound)
+	}
+	results := g.GetMany(peerContext(ctx), keys)
+	resp := &pb.MultiResponse{Items: make([]*pb.Item, len(results))}
+	for i, r := range results {
+		resp.Items[i] = toItem(r)
+	}
+	return resp, nil
+}
+
+// Set 是 rpc 服务要求的方法，将值写入当前节点
+func (s *server) Set(ctx context.Context, in *pb.SetRequest) (*pb.SetResponse, error) {
+	group, key := in.GetGroup(), in.GetKey()
+	log.Printf("[pcache server %s] Recv RPC Set - (%s)/(%s)", s.addr, group, key)
+	if key == "" {
+		return nil, toStatus(ErrKeyRequired)
+	}
+	g := GetGroup(group)
+	if g == nil {
+		return nil, toStatus(ErrGroupNotFound)
+	}
+	var expire time.Time
+	if in.GetExpire() != 0 {
+		expire = time.Unix(0, in.GetExpire())
+	}
+	g.setLocally(key, in.GetValue(), expire)
+	return &pb.SetResponse{}, nil
+}
+
+// Remove 是 rpc 服务要求的方法，删除当前节点中的缓存
+func (s *server) Remove(ctx context.Context, in *pb.Request) (*pb.RemoveResponse, error) {
+	group, key := in.GetGroup(), in.GetKey()
+	log.Printf("[pcache server %s] Recv RPC Remove - (%s)/(%s)", s.addr, group, key)
+	if key == "" {
+		return nil, toStatus(ErrKeyRequired)
+	}
+	g := GetGroup(group)
+	if g == nil {
+		return nil, toStatus(ErrGroupNotFound)
+	}
+	g.removeLocally(key)
+	return &pb.RemoveResponse{}, nil
+}
+
+// Start 启动服务器
+// 如果设置了注册中心，会将其注册到注册中心中，并根据节点变化更新哈希环；
+// 没有调用 SetRegistry 时不会注册到任何注册中心（不再默认连接本机的 etcd），只使用 SetPeers 设置的节点
 func (s *server) Start() error {
 	s.mu.Lock()
 	if s.status {
@@ -66,64 +162,409 @@ func (s *server) Start() error {
 		return fmt.Errorf("server already started")
 	}
 	s.status = true
-	s.stopSignal = make(chan error)
 
 	port := strings.Split(s.addr, ":")[1]
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
+		s.status = false
+		s.mu.Unlock()
 		return fmt.Errorf("failed to listen: %v", err)
 	}
+	if s.metricsAddr != "" {
+		if err := s.serveMetrics(); err != nil {
+			lis.Close()
+			s.status = false
+			s.mu.Unlock()
+			return err
+		}
+	}
 	grpcServer := grpc.NewServer()
 	pb.RegisterPcacheServer(grpcServer, s)
+	s.grpcServer = grpcServer
+	if s.reg != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.cancelWatch = cancel
+		s.registered = make(chan struct{})
+		s.watchDone = make(chan struct{})
+		go s.watchPeers(ctx, s.reg, s.watchDone)
+		go func(reg registry.Registry, member registry.Member, done chan struct{}) {
+			defer close(done)
+			ctx, cancel := context.WithTimeout(context.Background(), registerTimeout)
+			defer cancel()
+			if err := reg.Register(ctx, serviceName, member); err != nil {
+				log.Printf("[pcache server %s] register failed: %v", s.addr, err)
+			}
+		}(s.reg, registry.Member{Addr: s.addr, Weight: s.weight}, s.registered)
+	}
 	s.mu.Unlock()
-	if err := grpcServer.Serve(lis); s.status && err != nil {
+	// Stop 调用 GracefulStop 后 Serve 返回 nil，Stop 先于 Serve 调用时返回 ErrServerStopped
+	if err := grpcServer.Serve(lis); err != nil && err != grpc.ErrServerStopped {
 		return fmt.Errorf("failed to serve: %v", err)
 	}
 	return nil
 }
 
+// SetRegistry 设置节点使用的注册中心，需要在 Start 之前调用
+// Start 会将节点注册到 reg 中，并根据 reg 中的节点变化更新哈希环
+// 使用 etcd 时需要先通过 registry.NewEtcd 创建注册中心再调用 SetRegistry，Start 不会默认连接 etcd
+func (s *server) SetRegistry(reg registry.Registry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reg = reg
+}
+
+// SetWeight 设置节点注册到注册中心时携带的权重，需要在 Start 之前调用
+// 其他节点会按权重为它分配虚拟节点，例如内存是其他节点 8 倍的机器可以设置为 8
+func (s *server) SetWeight(weight int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.weight = weight
+}
+
+// SetPlacement 设置选择节点使用的放置算法，默认使用一致性哈希环
+// name 可以是 consistenthash 中的 PlacementRing、PlacementRendezvous、PlacementJump 或 PlacementMaglev
+// 需要在 SetPeers 和 Start 之前调用，已经有节点时返回错误
+func (s *server) SetPlacement(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) > 0 {
+		return fmt.Errorf("placement must be set before adding peers")
+	}
+	p, err := consistenthash.NewPlacement(name, defaultRepicas)
+	if err != nil {
+		return err
+	}
+	s.placement.Store(&p)
+	return nil
+}
+
+// SetBoundedLoad 开启有界负载模式，epsilon 为 0 时关闭
+// 开启后 Pick 会跳过负载超过平均负载 (1+epsilon) 倍的节点，负载来自本节点观察到的
+// 正在进行的请求数：当前节点为正在处理的 Get 请求数，其他节点为发往该节点的请求数
+// 只支持默认的哈希环，需要在 SetPlacement 之后调用
+func (s *server) SetBoundedLoad(epsilon float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ring, ok := s.currentPlacement().(*consistenthash.Map)
+	if !ok {
+		return fmt.Errorf("bounded load requires %s placement", consistenthash.PlacementRing)
+	}
+	if epsilon == 0 {
+		return ring.SetBoundedLoad(0, nil)
+	}
+	return ring.SetBoundedLoad(epsilon, s.load)
+}
+
+// currentPlacement 返回当前使用的放置算法，不需要持有锁
+func (s *server) currentPlacement() consistenthash.Placement {
+	return *s.placement.Load()
+}
+
+// load 返回节点当前的负载，不需要持有锁
+func (s *server) load(peerAddr string) int64 {
+	if peerAddr == s.addr {
+		return s.inflight.Load()
+	}
+	if c, ok := (*s.peers.Load())[peerAddr]; ok {
+		return c.inflight.Load()
+	}
+	return 0
+}
+
+// SetMetricsAddr 设置指标服务的监听地址，需要在 Start 之前调用
+// Start 会在该地址的 /metrics 路径以 Prometheus 文本格式提供指标
+func (s *server) SetMetricsAddr(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metricsAddr = addr
+}
+
+// serveMetrics 启动指标服务，调用时需要持有锁
+func (s *server) serveMetrics() error {
+	lis, err := net.Listen("tcp", s.metricsAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen metrics: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.MetricsHandler())
+	s.metricsServer = &http.Server{Handler: mux}
+	go func(srv *http.Server) {
+		if err := srv.Serve(lis); err != http.ErrServerClosed {
+			log.Printf("metrics server %s stopped: %v", s.metricsAddr, err)
+		}
+	}(s.metricsServer)
+	return nil
+}
+
+// ringSize 返回哈希环中的节点数
+func (s *server) ringSize() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// SetDialOptions 设置连接其他节点时额外使用的选项，只对之后创建的连接生效
+func (s *server) SetDialOptions(opts ...grpc.DialOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dialOpts = opts
+}
+
 // SetPeers 方法将服务实例注册到 Server 中
+// 仍然存在的节点会复用已有的连接并保留原有的权重，被移除的节点的连接会被关闭
 func (s *server) SetPeers(peerAddrs ...string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.consistentHash = consistenthash.New(defaultRepicas, nil)
-	s.consistentHash.Registe(peerAddrs...)
-	s.clients = make(map[string]*client)
+	keep := make(map[string]bool, len(peerAddrs))
+	added := make([]registry.Member, 0, len(peerAddrs))
+	placement := s.currentPlacement()
 	for _, peerAddr := range peerAddrs {
-		service := fmt.Sprintf("pcache%s", peerAddr)
-		s.clients[peerAddr] = NewClient(service)
+		keep[peerAddr] = true
+		// 已经存在的节点保留注册中心设置的权重，新的节点权重为 1
+		added = append(added, registry.Member{Addr: peerAddr, Weight: placement.Weight(peerAddr)})
 	}
+	var removed []string
+	for peerAddr := range s.clients {
+		if !keep[peerAddr] {
+			removed = append(removed, peerAddr)
+		}
+	}
+	s.updatePeersLocked(added, removed)
 }
 
-// Pick 使用一致性哈希算法选择 key 应使用的 cache
-// false 表示从本地获取
-func (s *server) Pick(key string) (Fetcher, bool) {
+// watchPeers 订阅注册中心中的节点变化，增量更新哈希环和连接，直到 ctx 被取消
+// 返回时关闭 done
+func (s *server) watchPeers(ctx context.Context, reg registry.Registry, done chan struct{}) {
+	defer close(done)
+	ch, err := reg.Watch(ctx, serviceName)
+	if err != nil {
+		log.Printf("[pcache server %s] watch peers failed: %v", s.addr, err)
+		return
+	}
+	for events := range ch {
+		var added []registry.Member
+		var removed []string
+		for _, e := range events {
+			switch e.Op {
+			case registry.Add:
+				added = append(added, e.Member)
+			case registry.Delete:
+				removed = append(removed, e.Member.Addr)
+			}
+		}
+		s.updatePeers(added, removed)
+	}
+}
+
+// updatePeers 增量地加入和移除节点，其余节点的连接保持不变
+// 已经存在的节点再次加入时会更新它的权重
+func (s *server) updatePeers(added []registry.Member, removed []string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.updatePeersLocked(added, removed)
+}
 
-	peerAddr := s.consistentHash.GetPeer(key)
-	if peerAddr == s.addr {
+// updatePeersLocked 是 updatePeers 的实现，调用时需要持有锁
+// 移除节点时先更新哈希环再关闭连接，加入节点时先创建连接再更新哈希环，
+// 保证无锁的 Pick 不会选中没有连接的节点
+func (s *server) updatePeersLocked(added []registry.Member, removed []string) {
+	var closed []*client
+	for _, peerAddr := range removed {
+		if c, ok := s.clients[peerAddr]; ok {
+			closed = append(closed, c)
+			delete(s.clients, peerAddr)
+			log.Printf("[pcache server %s] peer %s left", s.addr, peerAddr)
+		}
+	}
+	if len(closed) > 0 {
+		s.currentPlacement().Remove(removed...)
+		s.publish()
+		for _, c := range closed {
+			c.Close()
+		}
+	}
+	joined := false
+	for _, m := range added {
+		if _, ok := s.clients[m.Addr]; !ok {
+			s.clients[m.Addr] = NewClient(m.Addr, s.dialOpts...)
+			joined = true
+			log.Printf("[pcache server %s] peer %s joined with weight %d", s.addr, m.Addr, m.Weight)
+		}
+	}
+	if joined {
+		s.publish()
+	}
+	if len(added) > 0 {
+		weights := make(map[string]int, len(added))
+		for _, m := range added {
+			weights[m.Addr] = m.Weight
+		}
+		s.currentPlacement().RegisteWeights(weights)
+	}
+}
+
+// publish 发布 clients 的只读快照，调用时需要持有锁
+func (s *server) publish() {
+	peers := make(map[string]*client, len(s.clients))
+	for peerAddr, c := range s.clients {
+		peers[peerAddr] = c
+	}
+	s.peers.Store(&peers)
+}
+
+// Pick 使用放置算法选择 key 应使用的 cache
+// false 表示从本地获取
+// Pick 不需要持有锁，读取的是哈希环和连接的快照
+func (s *server) Pick(key string) (Fetcher, bool) {
+	peerAddr := s.currentPlacement().GetPeer(key)
+	if peerAddr == "" || peerAddr == s.addr {
 		log.Printf("pick self %s\n", s.addr)
 		return nil, false
 	}
+	c, ok := (*s.peers.Load())[peerAddr]
+	if !ok {
+		return nil, false
+	}
 	log.Printf("cache %s pick remote peer: %s\n", s.addr, peerAddr)
-	return s.clients[peerAddr], true
+	return c, true
+}
+
+// PickReplicas 按优先级返回 key 的前 n 个节点中排在当前节点之前的远程节点
+// 第一个节点与 Pick 选择的节点相同，不需要持有锁
+func (s *server) PickReplicas(key string, n int) []Fetcher {
+	placement := s.currentPlacement()
+	primary := placement.GetPeer(key)
+	if primary == "" {
+		return nil
+	}
+	addrs := []string{primary}
+	for _, peerAddr := range placement.GetPeers(key, n) {
+		if len(addrs) < n && peerAddr != primary {
+			addrs = append(addrs, peerAddr)
+		}
+	}
+	peers := *s.peers.Load()
+	var fetchers []Fetcher
+	for _, peerAddr := range addrs {
+		if peerAddr == s.addr {
+			break
+		}
+		if c, ok := peers[peerAddr]; ok {
+			fetchers = append(fetchers, c)
+		}
+	}
+	return fetchers
 }
 
-// 停止 server 运行
-func (s *server) Stop() {
+// Replicas 返回 key 的前 n 个节点和 Pick 选择的节点中除当前节点之外的所有远程节点，不需要持有锁
+// 与 PickReplicas 不同，排在当前节点之后的副本节点也会返回，Group.Set 和 Remove 使用它修改所有副本
+func (s *server) Replicas(key string, n int) []Fetcher {
+	placement := s.currentPlacement()
+	primary := placement.GetPeer(key)
+	if primary == "" {
+		return nil
+	}
+	peers := *s.peers.Load()
+	var fetchers []Fetcher
+	for i, peerAddr := range append([]string{primary}, placement.GetPeers(key, n)...) {
+		if peerAddr == s.addr || (i > 0 && peerAddr == primary) {
+			continue
+		}
+		if c, ok := peers[peerAddr]; ok {
+			fetchers = append(fetchers, c)
+		}
+	}
+	return fetchers
+}
+
+// Fetchers 返回除当前节点之外的所有远程节点
+func (s *server) Fetchers() []Fetcher {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fetchers := make([]Fetcher, 0, len(s.clients))
+	for peerAddr, c := range s.clients {
+		if peerAddr != s.addr {
+			fetchers = append(fetchers, c)
+		}
+	}
+	return fetchers
+}
+
+// Stop 停止 server 运行，所有工作完成后返回，Start 随之返回
+// 依次从注册中心注销、停止订阅节点变化、等待正在处理的请求完成和 watchPeers 退出、关闭到其他节点的连接
+// 注销使用单独的 registerTimeout 超时，不受 ctx 影响
+// ctx 结束时不再等待正在处理的请求，强制关闭服务并返回 ctx.Err()
+func (s *server) Stop(ctx context.Context) error {
 	s.mu.Lock()
 	if !s.status {
 		s.mu.Unlock()
-		return
+		return nil
 	}
-	s.stopSignal <- nil // 停止发送 KeepAlive 信号
-	s.status = false    // 设置服务状态为 stop
-	s.clients = nil
-	s.consistentHash = nil
+	s.status = false // 设置服务状态为 stop
+	reg, registered, cancelWatch, watchDone := s.reg, s.registered, s.cancelWatch, s.watchDone
+	grpcServer, metricsServer := s.grpcServer, s.metricsServer
+	s.registered, s.cancelWatch, s.watchDone = nil, nil, nil
+	s.grpcServer, s.metricsServer = nil, nil
 	s.mu.Unlock()
+
+	var err error
+	if cancelWatch != nil {
+		cancelWatch() // 停止订阅节点变化
+		// 等待 Start 中的注册完成，避免注销之后才写入注册记录
+		select {
+		case <-registered:
+		case <-ctx.Done():
+		}
+		// 使用单独的超时时间，ctx 已经结束时也要尽量删除注册记录，避免其他节点继续转发请求到这里
+		dctx, cancel := context.WithTimeout(context.Background(), registerTimeout)
+		if derr := reg.Deregister(dctx, serviceName, s.addr); derr != nil {
+			log.Printf("[pcache server %s] deregister failed: %v", s.addr, derr)
+		}
+		cancel()
+	}
+
+	// 先停止接收新的请求，再等待正在处理的请求完成
+	done := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		grpcServer.Stop()
+		<-done
+		err = ctx.Err()
+	}
+	if metricsServer != nil {
+		if serr := metricsServer.Shutdown(ctx); serr != nil {
+			metricsServer.Close()
+		}
+	}
+
+	// 等待 watchPeers 退出，避免清理之后它又加入节点和连接
+	if watchDone != nil {
+		<-watchDone
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	placement := s.currentPlacement()
+	placement.Remove(placement.Peers()...)
+	clients := s.clients
+	s.clients = make(map[string]*client)
+	s.publish()
+	for _, c := range clients {
+		c.Close()
+	}
+	return err
 }
 
-// 要求 Server 实现 Picker 接口
-var _ Picker = (*server)(nil)
+// 要求 Server 实现 Picker、ReplicaPicker 和 PeerLister 接口
+var (
+	_ Picker        = (*server)(nil)
+	_ ReplicaPicker = (*server)(nil)
+	_ PeerLister    = (*server)(nil)
+)
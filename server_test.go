@@ -0,0 +1,417 @@
+package pcache
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"pcache/consistenthash"
+	"pcache/registry"
+)
+
+func TestSetPeersKeepsClients(t *testing.T) {
+	s, _ := NewServer("127.0.0.1:6324")
+	s.SetPeers("127.0.0.1:6324", "127.0.0.1:6325")
+	kept, removed := s.clients["127.0.0.1:6324"], s.clients["127.0.0.1:6325"]
+	s.SetPeers("127.0.0.1:6324", "127.0.0.1:6326")
+	if s.clients["127.0.0.1:6324"] != kept {
+		t.Fatal("client of remaining peer should be reused")
+	}
+	if !removed.closed {
+		t.Fatal("client of removed peer should be closed")
+	}
+}
+
+func TestUpdatePeers(t *testing.T) {
+	s, _ := NewServer("127.0.0.1:6324")
+	s.updatePeers([]registry.Member{{Addr: "127.0.0.1:6324"}, {Addr: "127.0.0.1:6325"}}, nil)
+	kept := s.clients["127.0.0.1:6324"]
+	removed := s.clients["127.0.0.1:6325"]
+	s.updatePeers([]registry.Member{{Addr: "127.0.0.1:6326"}}, []string{"127.0.0.1:6325"})
+	if len(s.clients) != 2 || s.clients["127.0.0.1:6324"] != kept {
+		t.Fatalf("remaining peers should keep their clients, but got %v", s.clients)
+	}
+	if !removed.closed {
+		t.Fatal("client of removed peer should be closed")
+	}
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		if peer := s.currentPlacement().GetPeer(key); peer == "127.0.0.1:6325" {
+			t.Fatalf("%s should not be picked on removed peer", key)
+		}
+	}
+}
+
+func TestPickWithoutPeers(t *testing.T) {
+	s, _ := NewServer("127.0.0.1:6324")
+	if _, ok := s.Pick("Tom"); ok {
+		t.Fatal("server without peers should pick itself")
+	}
+}
+
+func TestWatchPeers(t *testing.T) {
+	reg := registry.NewMemory()
+	ctx := context.Background()
+	reg.Register(ctx, serviceName, registry.Member{Addr: "127.0.0.1:6324"})
+	reg.Register(ctx, serviceName, registry.Member{Addr: "127.0.0.1:6325"})
+
+	s, _ := NewServer("127.0.0.1:6324")
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.watchPeers(ctx, reg, make(chan struct{}))
+	waitPeers(t, s, 2)
+
+	reg.Register(ctx, serviceName, registry.Member{Addr: "127.0.0.1:6326"})
+	waitPeers(t, s, 3)
+	reg.Deregister(ctx, serviceName, "127.0.0.1:6325")
+	waitPeers(t, s, 2)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients["127.0.0.1:6325"]; ok {
+		t.Fatal("removed peer should not have a client")
+	}
+}
+
+// waitPeers 等待 s 的节点数量变为 n
+func waitPeers(t *testing.T, s *server, n int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if s.ringSize() == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("want %d peers, but got %d", n, s.ringSize())
+}
+
+func TestPickDuringUpdate(t *testing.T) {
+	s, _ := NewServer("127.0.0.1:6324")
+	s.SetPeers("127.0.0.1:6324", "127.0.0.1:6325")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			s.updatePeers([]registry.Member{{Addr: "127.0.0.1:6326"}}, nil)
+			s.updatePeers(nil, []string{"127.0.0.1:6326"})
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if f, ok := s.Pick("Tom"); ok && f == nil {
+			t.Fatal("picked peer should have a client")
+		}
+	}
+}
+
+func TestWatchPeersWeight(t *testing.T) {
+	reg := registry.NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reg.Register(ctx, serviceName, registry.Member{Addr: "127.0.0.1:6324"})
+	reg.Register(ctx, serviceName, registry.Member{Addr: "127.0.0.1:6325", Weight: 4})
+
+	s, _ := NewServer("127.0.0.1:6324")
+	go s.watchPeers(ctx, reg, make(chan struct{}))
+	waitPeers(t, s, 2)
+	if w := s.currentPlacement().Weight("127.0.0.1:6325"); w != 4 {
+		t.Fatalf("want weight 4, but got %d", w)
+	}
+
+	reg.Register(ctx, serviceName, registry.Member{Addr: "127.0.0.1:6325", Weight: 2})
+	for i := 0; i < 100 && s.currentPlacement().Weight("127.0.0.1:6325") != 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if w := s.currentPlacement().Weight("127.0.0.1:6325"); w != 2 {
+		t.Fatalf("want weight 2 after update, but got %d", w)
+	}
+
+	// SetPeers 保留已有节点的权重
+	s.SetPeers("127.0.0.1:6324", "127.0.0.1:6325", "127.0.0.1:6326")
+	if w := s.currentPlacement().Weight("127.0.0.1:6325"); w != 2 {
+		t.Fatalf("SetPeers should keep weight 2, but got %d", w)
+	}
+	if w := s.currentPlacement().Weight("127.0.0.1:6326"); w != 1 {
+		t.Fatalf("new peer should have weight 1, but got %d", w)
+	}
+}
+
+func TestSetPlacement(t *testing.T) {
+	s, _ := NewServer("127.0.0.1:6324")
+	if err := s.SetPlacement("unknown"); err == nil {
+		t.Fatal("unknown placement should return an error")
+	}
+	if err := s.SetPlacement(consistenthash.PlacementMaglev); err != nil {
+		t.Fatal(err)
+	}
+	s.SetPeers("127.0.0.1:6324", "127.0.0.1:6325")
+	if _, ok := s.currentPlacement().(*consistenthash.Maglev); !ok {
+		t.Fatalf("want maglev placement, but got %T", s.currentPlacement())
+	}
+	if peers := s.currentPlacement().Peers(); len(peers) != 2 {
+		t.Fatalf("want 2 peers, but got %v", peers)
+	}
+	if err := s.SetPlacement(consistenthash.PlacementJump); err == nil {
+		t.Fatal("set placement after adding peers should return an error")
+	}
+
+	// SetPlacement 与并发的 Pick 不会产生数据竞争
+	s, _ = NewServer("127.0.0.1:6324")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			s.Pick("Tom")
+		}
+	}()
+	for _, name := range []string{consistenthash.PlacementRendezvous, consistenthash.PlacementRing} {
+		if err := s.SetPlacement(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+func TestSetBoundedLoad(t *testing.T) {
+	s, _ := NewServer("127.0.0.1:6324")
+	if err := s.SetBoundedLoad(0.25); err != nil {
+		t.Fatal(err)
+	}
+	s.SetPeers("127.0.0.1:6324", "127.0.0.1:6325")
+	f, remote := s.Pick("Tom")
+	// 让 Tom 的所属节点处于高负载，Pick 应当选择另一个节点
+	if remote {
+		f.(*client).inflight.Add(10)
+	} else {
+		s.inflight.Add(10)
+	}
+	if _, ok := s.Pick("Tom"); ok == remote {
+		t.Fatal("overloaded peer should be skipped")
+	}
+
+	s2, _ := NewServer("127.0.0.1:6324")
+	s2.SetPlacement(consistenthash.PlacementJump)
+	if err := s2.SetBoundedLoad(0.25); err == nil {
+		t.Fatal("bounded load with jump placement should return an error")
+	}
+}
+
+func TestPickReplicas(t *testing.T) {
+	s, _ := NewServer("127.0.0.1:6324")
+	if fetchers := s.PickReplicas("Tom", 2); len(fetchers) != 0 {
+		t.Fatalf("server without peers should pick itself, but got %v", fetchers)
+	}
+	peers := []string{"127.0.0.1:6324", "127.0.0.1:6325", "127.0.0.1:6326"}
+	s.SetPeers(peers...)
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		order := s.currentPlacement().GetPeers(key, 3)
+		fetchers := s.PickReplicas(key, 3)
+		// 只返回排在当前节点之前的远程节点
+		var want []string
+		for _, peerAddr := range order {
+			if peerAddr == s.addr {
+				break
+			}
+			want = append(want, peerAddr)
+		}
+		if len(fetchers) != len(want) {
+			t.Fatalf("%s: want %v, but got %d fetchers", key, want, len(fetchers))
+		}
+		for j, f := range fetchers {
+			if f.(*client).addr != want[j] {
+				t.Fatalf("%s: want %v, but got %s at %d", key, want, f.(*client).addr, j)
+			}
+		}
+		// Replicas 返回所有副本中的远程节点，包括排在当前节点之后的节点
+		want = want[:0]
+		for _, peerAddr := range s.currentPlacement().GetPeers(key, 2) {
+			if peerAddr != s.addr {
+				want = append(want, peerAddr)
+			}
+		}
+		replicas := s.Replicas(key, 2)
+		if len(replicas) != len(want) {
+			t.Fatalf("%s: want replicas %v, but got %d fetchers", key, want, len(replicas))
+		}
+		for j, f := range replicas {
+			if f.(*client).addr != want[j] {
+				t.Fatalf("%s: want replicas %v, but got %s at %d", key, want, f.(*client).addr, j)
+			}
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+
+	reg := registry.NewMemory()
+	s, _ := NewServer(addr)
+	s.SetRegistry(reg)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("stop a server which is not running should return nil, but got %v", err)
+	}
+	started := make(chan error, 1)
+	go func() {
+		started <- s.Start()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _ := reg.Watch(ctx, serviceName)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("server should register itself")
+	}
+	waitPeers(t, s, 1)
+	s.mu.Lock()
+	c := s.clients[addr]
+	s.mu.Unlock()
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatalf("Start should return nil after Stop, but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start should return after Stop")
+	}
+	select {
+	case events := <-ch:
+		if events[0].Op != registry.Delete {
+			t.Fatalf("server should deregister itself, but got %v", events)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server should deregister itself")
+	}
+	if !c.closed {
+		t.Fatal("peer connections should be closed")
+	}
+	if _, ok := s.Pick("Tom"); ok {
+		t.Fatal("stopped server should not pick remote peers")
+	}
+}
+
+// cancelRegistry 在 ctx 结束时拒绝注销，用于检查 Stop 注销时不使用调用方的 ctx
+type cancelRegistry struct {
+	*registry.Memory
+}
+
+func (r cancelRegistry) Deregister(ctx context.Context, service string, addr string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.Memory.Deregister(ctx, service, addr)
+}
+
+func TestStopCanceled(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+
+	reg := cancelRegistry{registry.NewMemory()}
+	s, _ := NewServer(addr)
+	s.SetRegistry(reg)
+	go s.Start()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _ := reg.Watch(ctx, serviceName)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("server should register itself")
+	}
+	waitPeers(t, s, 1)
+
+	stopCtx, stop := context.WithCancel(context.Background())
+	stop()
+	s.Stop(stopCtx)
+	select {
+	case events := <-ch:
+		if events[0].Op != registry.Delete {
+			t.Fatalf("server should deregister itself, but got %v", events)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server should deregister itself even if ctx is done")
+	}
+}
+
+// lateRegistry 在 ctx 取消后过一段时间才发送最后一批事件并关闭 Watch 返回的 channel
+type lateRegistry struct {
+	*registry.Memory
+	closed chan struct{} // closed 在 Watch 返回的 channel 关闭后关闭
+}
+
+func (r lateRegistry) Watch(ctx context.Context, service string) (<-chan []registry.Event, error) {
+	ch := make(chan []registry.Event)
+	go func() {
+		defer close(r.closed)
+		defer close(ch)
+		<-ctx.Done()
+		time.Sleep(100 * time.Millisecond)
+		ch <- []registry.Event{{Op: registry.Add, Member: registry.Member{Addr: "127.0.0.1:6399"}}}
+	}()
+	return ch, nil
+}
+
+func TestStopWaitWatch(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+
+	reg := lateRegistry{registry.NewMemory(), make(chan struct{})}
+	s, _ := NewServer(addr)
+	s.SetRegistry(reg)
+	go s.Start()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _ := reg.Memory.Watch(ctx, serviceName)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("server should register itself")
+	}
+
+	s.Stop(context.Background())
+	select {
+	case <-reg.closed:
+	default:
+		t.Fatal("Stop should wait for watchPeers to return")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) != 0 {
+		t.Fatalf("stopped server should have no clients, but got %v", len(s.clients))
+	}
+}
+
+func TestDestroyGroupWithoutServer(t *testing.T) {
+	NewGroup("destroy-test", 0, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	DestroyGroup("destroy-test")
+	if GetGroup("destroy-test") != nil {
+		t.Fatal("group should be destroyed")
+	}
+}
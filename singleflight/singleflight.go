@@ -1,39 +1,274 @@
 package singleflight
 
-import "sync"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrGoexit 表示 fn 调用了 runtime.Goexit，FlyChan 的调用方会收到该错误
+var ErrGoexit = errors.New("runtime.Goexit was called")
+
+// panicError 保存 fn 中 panic 的值和发生 panic 时的堆栈
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *panicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+func newPanicError(v interface{}) error {
+	stack := debug.Stack()
+	// 第一行是 "goroutine N [status]:"，去掉它避免误导，因为该 goroutine 可能已经退出
+	if line := bytes.IndexByte(stack, '\n'); line >= 0 {
+		stack = stack[line+1:]
+	}
+	return &panicError{value: v, stack: stack}
+}
+
+// Result 是 FlyChan 返回的结果
+type Result struct {
+	Val    interface{}
+	Err    error
+	Shared bool // Shared 表示结果是否被多个调用方共享
+}
 
 type packet struct {
-	wg  sync.WaitGroup
-	val interface{}
-	err error
+	done  chan struct{} // done 在 fn 结束后关闭
+	val   interface{}
+	err   error
+	dups  int             // dups 是等待该结果的其他调用方的数量
+	chans []chan<- Result // chans 是 FlyChan 调用方的 channel
+
+	waiting int                // waiting 是 FlyDetached 发起的调用中还在等待结果的调用方数量
+	cancel  context.CancelFunc // cancel 取消 FlyDetached 中 fn 使用的 ctx，其他方式发起的调用为 nil
 }
 
+// Flight 保证同一个 key 同时只有一次 fn 在执行，其余的调用方等待并共享结果
 type Flight struct {
 	mu     sync.Mutex
 	flight map[string]*packet
 }
 
+// Fly 执行 fn 并返回结果，同一个 key 正在执行时等待并共享已有的结果
+// 需要知道结果是否被共享时使用 FlyContext 或 FlyChan
+// fn panic 时所有等待的调用方都会以相同的值 panic，fn 调用 runtime.Goexit 时所有调用方都会退出
 func (f *Flight) Fly(key string, fn func() (interface{}, error)) (interface{}, error) {
+	v, err, _ := f.FlyContext(context.Background(), key, fn)
+	return v, err
+}
+
+// FlyContext 与 Fly 相同，但等待其他调用方的结果时可以因为 ctx 结束而放弃，返回 ctx.Err()
+// shared 表示结果是否被多个调用方共享
+// 放弃等待不影响正在执行的 fn，执行 fn 的调用方需要自己在 fn 中处理 ctx
+func (f *Flight) FlyContext(ctx context.Context, key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
 	f.mu.Lock()
 	if f.flight == nil {
 		f.flight = make(map[string]*packet)
 	}
 	if p, ok := f.flight[key]; ok {
+		p.dups++
+		if p.cancel != nil {
+			p.waiting++
+		}
 		f.mu.Unlock()
-		p.wg.Wait()
-		return p.val, p.err
+		v, err, done := f.wait(ctx, key, p)
+		return v, err, done
 	}
-	p := new(packet)
-	p.wg.Add(1)
+	p := &packet{done: make(chan struct{})}
 	f.flight[key] = p
 	f.mu.Unlock()
 
-	p.val, p.err = fn()
-	p.wg.Done()
+	f.run(p, key, fn)
+	return p.val, p.err, p.dups > 0
+}
+
+// FlyDetached 与 FlyContext 相同，但 fn 在新的 goroutine 中使用独立于调用方的 ctx 执行
+// fn 的 ctx 保留发起调用的 ctx 中的值，但不会随它结束。每个调用方都可以因为自己的 ctx 结束而放弃等待，
+// 所有调用方都放弃之后 fn 的 ctx 才会被取消，之后对 key 的调用会重新执行 fn。
+// fn panic 或调用 runtime.Goexit 时，等待的调用方同样会 panic 或退出
+func (f *Flight) FlyDetached(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (v interface{}, err error, shared bool) {
+	return f.Join(ctx, key, fn).Wait(ctx)
+}
+
+// Call 是 Join 发起或加入的一次调用
+type Call struct {
+	f      *Flight
+	key    string
+	p      *packet
+	leader bool
+}
 
+// Join 与 FlyDetached 相同，但不等待结果，调用方之后需要调用 Wait 等待结果或放弃
+// 调用方可以根据 Leader 知道 fn 是否由自己发起，例如在 fn 之外为它准备结果
+func (f *Flight) Join(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) *Call {
+	f.mu.Lock()
+	if f.flight == nil {
+		f.flight = make(map[string]*packet)
+	}
+	if p, ok := f.flight[key]; ok {
+		p.dups++
+		if p.cancel != nil {
+			p.waiting++
+		}
+		f.mu.Unlock()
+		return &Call{f: f, key: key, p: p}
+	}
+	fctx, cancel := context.WithCancel(WithoutCancel(ctx))
+	p := &packet{done: make(chan struct{}), waiting: 1, cancel: cancel}
+	f.flight[key] = p
+	f.mu.Unlock()
+
+	go f.run(p, key, func() (interface{}, error) {
+		return fn(fctx)
+	})
+	return &Call{f: f, key: key, p: p, leader: true}
+}
+
+// Leader 返回 fn 是否由这次调用发起
+func (c *Call) Leader() bool {
+	return c.leader
+}
+
+// Wait 等待调用的结果，ctx 结束时放弃等待并返回 ctx.Err()
+// shared 表示结果是否被多个调用方共享
+func (c *Call) Wait(ctx context.Context) (v interface{}, err error, shared bool) {
+	v, err, done := c.f.wait(ctx, c.key, c.p)
+	return v, err, done && (!c.leader || c.p.dups > 0)
+}
+
+// WithoutCancel 返回保留 parent 中的值，但没有截止时间，也不会随 parent 结束的 ctx
+// 与 Go 1.21 中的 context.WithoutCancel 相同
+func WithoutCancel(parent context.Context) context.Context {
+	return detachedContext{parent}
+}
+
+// detachedContext 是 WithoutCancel 返回的 ctx
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) { return }
+func (detachedContext) Done() <-chan struct{}                   { return nil }
+func (detachedContext) Err() error                              { return nil }
+func (c detachedContext) Value(key interface{}) interface{}     { return c.parent.Value(key) }
+
+// wait 等待 p 的结果，ctx 结束时放弃等待并返回 ctx.Err()，done 为 false 表示放弃了等待
+// FlyDetached 发起的调用的所有调用方都放弃之后，取消 fn 的 ctx，并让之后的调用重新执行 fn
+func (f *Flight) wait(ctx context.Context, key string, p *packet) (v interface{}, err error, done bool) {
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		f.mu.Lock()
+		if p.cancel != nil {
+			if p.waiting--; p.waiting == 0 {
+				p.cancel()
+				if f.flight[key] == p {
+					delete(f.flight, key)
+				}
+			}
+		}
+		f.mu.Unlock()
+		return nil, ctx.Err(), false
+	}
+	if e, ok := p.err.(*panicError); ok {
+		panic(e)
+	} else if p.err == ErrGoexit {
+		runtime.Goexit()
+	}
+	return p.val, p.err, true
+}
+
+// FlyChan 与 Fly 相同，但不阻塞，结果会被发送到返回的 channel 中
+// fn 在新的 goroutine 中执行，fn panic 时无法恢复，进程会崩溃，而不是让调用方永远等待
+func (f *Flight) FlyChan(key string, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+	f.mu.Lock()
+	if f.flight == nil {
+		f.flight = make(map[string]*packet)
+	}
+	if p, ok := f.flight[key]; ok {
+		p.dups++
+		p.chans = append(p.chans, ch)
+		// FlyChan 的调用方不会放弃等待
+		if p.cancel != nil {
+			p.waiting++
+		}
+		f.mu.Unlock()
+		return ch
+	}
+	p := &packet{done: make(chan struct{}), chans: []chan<- Result{ch}}
+	f.flight[key] = p
+	f.mu.Unlock()
+
+	go f.run(p, key, fn)
+	return ch
+}
+
+// Forget 让之后对 key 的调用重新执行 fn，而不是等待正在执行的 fn
+func (f *Flight) Forget(key string) {
 	f.mu.Lock()
 	delete(f.flight, key)
 	f.mu.Unlock()
+}
+
+// run 执行 fn 并通知所有等待的调用方
+// 需要区分 fn 正常返回、panic 和调用 runtime.Goexit 三种情况
+func (f *Flight) run(p *packet, key string, fn func() (interface{}, error)) {
+	normalReturn := false
+	recovered := false
+
+	defer func() {
+		// 既没有正常返回也没有 recover，说明 fn 调用了 runtime.Goexit
+		if !normalReturn && !recovered {
+			p.err = ErrGoexit
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		close(p.done)
+		if f.flight[key] == p {
+			delete(f.flight, key)
+		}
+		if p.cancel != nil {
+			p.cancel()
+		}
 
-	return p.val, p.err
+		// FlyDetached 发起的调用在单独的 goroutine 中执行，由等待的调用方 panic
+		if e, ok := p.err.(*panicError); ok && p.cancel == nil {
+			if len(p.chans) > 0 {
+				// FlyChan 的调用方无法接收 panic，让进程崩溃，避免它们永远等待
+				go panic(e)
+				select {}
+			}
+			panic(e)
+		}
+		for _, ch := range p.chans {
+			ch <- Result{Val: p.val, Err: p.err, Shared: p.dups > 0}
+		}
+	}()
+
+	func() {
+		defer func() {
+			if !normalReturn {
+				// recover 对 runtime.Goexit 返回 nil
+				if r := recover(); r != nil {
+					p.err = newPanicError(r)
+				}
+			}
+		}()
+		p.val, p.err = fn()
+		normalReturn = true
+	}()
+
+	if !normalReturn {
+		recovered = true
+	}
 }
@@ -0,0 +1,247 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitDups 等待 key 有 n 个调用方在等待结果
+func waitDups(f *Flight, key string, n int) {
+	for {
+		f.mu.Lock()
+		p := f.flight[key]
+		dups := 0
+		if p != nil {
+			dups = p.dups
+		}
+		f.mu.Unlock()
+		if dups == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFly(t *testing.T) {
+	var f Flight
+	v, err := f.Fly("key", func() (interface{}, error) {
+		return "bar", nil
+	})
+	if v.(string) != "bar" || err != nil {
+		t.Fatalf("got %v, %v; expect bar, nil", v, err)
+	}
+}
+
+func TestFlyDuplicates(t *testing.T) {
+	var f Flight
+	var calls int32
+	start := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-start
+		return "bar", nil
+	}
+	var wg sync.WaitGroup
+	var shared int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, s := f.FlyContext(context.Background(), "key", fn)
+			if v.(string) != "bar" || err != nil {
+				t.Errorf("got %v, %v; expect bar, nil", v, err)
+			}
+			if s {
+				atomic.AddInt32(&shared, 1)
+			}
+		}()
+	}
+	waitDups(&f, "key", 9)
+	close(start)
+	wg.Wait()
+	if calls != 1 || shared != 10 {
+		t.Fatalf("got %d calls and %d shared results; expect 1 and 10", calls, shared)
+	}
+}
+
+func TestFlyContextCancel(t *testing.T) {
+	var f Flight
+	start, done := make(chan struct{}), make(chan struct{})
+	go f.Fly("key", func() (interface{}, error) {
+		close(start)
+		<-done
+		return "bar", nil
+	})
+	<-start
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err, _ := f.FlyContext(ctx, "key", func() (interface{}, error) {
+		t.Error("fn should not be called while key is in flight")
+		return nil, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v; expect %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestFlyDetached(t *testing.T) {
+	var f Flight
+	start, done := make(chan struct{}), make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(start)
+		select {
+		case <-done:
+			return "bar", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// 发起调用的调用方放弃之后，其他调用方仍然可以得到结果
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err, _ := f.FlyDetached(ctx, "key", fn)
+		leader <- err
+	}()
+	<-start
+	result := make(chan interface{})
+	go func() {
+		v, _, _ := f.FlyDetached(context.Background(), "key", fn)
+		result <- v
+	}()
+	waitDups(&f, "key", 1)
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v; expect %v", err, context.Canceled)
+	}
+	close(done)
+	if v := <-result; v != "bar" {
+		t.Fatalf("got %v; expect bar", v)
+	}
+
+	// 所有调用方都放弃之后 fn 的 ctx 被取消，之后的调用重新执行 fn
+	canceled := make(chan error, 1)
+	ctx, cancel = context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go f.FlyDetached(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	<-started
+	cancel()
+	if err := <-canceled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v; expect fn's context canceled", err)
+	}
+	v, err, _ := f.FlyDetached(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return "fresh", nil
+	})
+	if v != "fresh" || err != nil {
+		t.Fatalf("got %v, %v; expect a fresh call", v, err)
+	}
+}
+
+func TestFlyChan(t *testing.T) {
+	var f Flight
+	start := make(chan struct{})
+	ch1 := f.FlyChan("key", func() (interface{}, error) {
+		<-start
+		return "bar", nil
+	})
+	ch2 := f.FlyChan("key", func() (interface{}, error) {
+		t.Error("fn should not be called while key is in flight")
+		return nil, nil
+	})
+	close(start)
+	for _, ch := range []<-chan Result{ch1, ch2} {
+		r := <-ch
+		if r.Val.(string) != "bar" || r.Err != nil || !r.Shared {
+			t.Fatalf("got %+v; expect shared bar", r)
+		}
+	}
+}
+
+func TestForget(t *testing.T) {
+	var f Flight
+	start, done := make(chan struct{}), make(chan struct{})
+	go f.Fly("key", func() (interface{}, error) {
+		close(start)
+		<-done
+		return 1, nil
+	})
+	<-start
+	defer close(done)
+
+	f.Forget("key")
+	v, _, shared := f.FlyContext(context.Background(), "key", func() (interface{}, error) {
+		return 2, nil
+	})
+	if v.(int) != 2 || shared {
+		t.Fatalf("got %v, shared %v; expect a fresh call after Forget", v, shared)
+	}
+}
+
+func TestFlyPanic(t *testing.T) {
+	var f Flight
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	panics := make(chan interface{}, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				panics <- recover()
+			}()
+			f.Fly("key", func() (interface{}, error) {
+				<-start
+				panic("boom")
+			})
+		}()
+	}
+	waitDups(&f, "key", 1)
+	close(start)
+	wg.Wait()
+	close(panics)
+	for r := range panics {
+		err, ok := r.(error)
+		if !ok || !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("every caller should panic with boom, but got %v", r)
+		}
+	}
+	if _, err := f.Fly("key", func() (interface{}, error) { return nil, nil }); err != nil {
+		t.Fatal("key should be released after panic")
+	}
+}
+
+func TestFlyGoexit(t *testing.T) {
+	var f Flight
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Fly("key", func() (interface{}, error) {
+			runtime.Goexit()
+			return nil, nil
+		})
+		t.Error("Fly should not return after Goexit")
+	}()
+	<-done
+
+	r := <-f.FlyChan("other", func() (interface{}, error) {
+		runtime.Goexit()
+		return nil, nil
+	})
+	if r.Err != ErrGoexit {
+		t.Fatalf("got %v; expect %v", r.Err, ErrGoexit)
+	}
+}
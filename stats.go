@@ -0,0 +1,55 @@
+package pcache
+
+import "sync/atomic"
+
+// GroupStats 是 Group 统计信息的快照
+type GroupStats struct {
+	Gets          int64 // Gets 是 Get 请求的次数
+	Hits          int64 // Hits 是命中 mainCache 或 hotCache 的次数
+	Misses        int64 // Misses 是没有命中缓存的次数
+	NegativeHits  int64 // NegativeHits 是命中不存在的 key 的缓存、直接返回 ErrNotFound 的次数
+	PeerLoads     int64 // PeerLoads 是从远程节点获取成功的次数
+	PeerErrors    int64 // PeerErrors 是从远程节点获取失败的次数
+	LocalLoads    int64 // LocalLoads 是从数据源获取成功的次数
+	LocalLoadErrs int64 // LocalLoadErrs 是从数据源获取失败的次数
+	Dedups        int64 // Dedups 是被 singleflight 合并、没有真正执行加载的请求次数
+	Batches       int64 // Batches 是合并并发未命中后调用 BatchGetter 的次数
+	Evictions     int64 // Evictions 是 mainCache 和 hotCache 淘汰的条目总数
+
+	MainCache CacheStats // MainCache 是 mainCache 的统计信息
+	HotCache  CacheStats // HotCache 是 hotCache 的统计信息
+}
+
+// groupStats 保存 Group 的原子计数器
+type groupStats struct {
+	gets          atomic.Int64
+	hits          atomic.Int64
+	misses        atomic.Int64
+	negativeHits  atomic.Int64
+	peerLoads     atomic.Int64
+	peerErrors    atomic.Int64
+	localLoads    atomic.Int64
+	localLoadErrs atomic.Int64
+	dedups        atomic.Int64
+	batches       atomic.Int64
+}
+
+// Stats 返回 Group 当前统计信息的快照
+func (g *Group) Stats() GroupStats {
+	stats := GroupStats{
+		Gets:          g.stats.gets.Load(),
+		Hits:          g.stats.hits.Load(),
+		Misses:        g.stats.misses.Load(),
+		NegativeHits:  g.stats.negativeHits.Load(),
+		PeerLoads:     g.stats.peerLoads.Load(),
+		PeerErrors:    g.stats.peerErrors.Load(),
+		LocalLoads:    g.stats.localLoads.Load(),
+		LocalLoadErrs: g.stats.localLoadErrs.Load(),
+		Dedups:        g.stats.dedups.Load(),
+		Batches:       g.stats.batches.Load(),
+		MainCache:     g.CacheStats(MainCache),
+		HotCache:      g.CacheStats(HotCache),
+	}
+	stats.Evictions = stats.MainCache.Evictions + stats.HotCache.Evictions
+	return stats
+}
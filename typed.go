@@ -0,0 +1,141 @@
+package pcache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TypedGetter 从数据源获取 key 对应的对象
+type TypedGetter[T any] interface {
+	Get(ctx context.Context, key string) (T, error)
+}
+
+// TypedGetterFunc 实现了 TypedGetter 接口
+type TypedGetterFunc[T any] func(ctx context.Context, key string) (T, error)
+
+func (f TypedGetterFunc[T]) Get(ctx context.Context, key string) (T, error) {
+	return f(ctx, key)
+}
+
+// TypedResult 是 TypedGroup.GetMany 中一个 key 的结果
+type TypedResult[T any] struct {
+	Value T
+	Err   error
+}
+
+// TypedGroup 在 Group 的基础上使用 Codec 读写 T 类型的对象
+// 节点之间仍然传输编码后的字节，当前节点缓存的条目会同时保存解码后的对象，
+// 命中缓存时不会重新解码。返回的对象被所有调用方共享，调用方不能修改它
+// 解码后的对象不计入 GroupOptions.MaxBytes，按字节数限制时需要为对象预留额外的内存
+type TypedGroup[T any] struct {
+	group *Group
+	codec Codec[T]
+}
+
+// typedGetter 将 TypedGetter 适配为 Group 使用的 Getter
+type typedGetter[T any] struct {
+	getter TypedGetter[T]
+	codec  Codec[T]
+}
+
+func (g typedGetter[T]) Get(key string) ([]byte, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+func (g typedGetter[T]) GetContext(ctx context.Context, key string) ([]byte, error) {
+	v, err := g.getter.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return g.codec.Encode(v)
+}
+
+// NewTypedGroup 创建一个 TypedGroup 实例，底层的 Group 会注册到 groups 中
+func NewTypedGroup[T any](name string, maxEntries int, codec Codec[T], getter TypedGetter[T]) *TypedGroup[T] {
+	g, err := NewTypedGroupWithOptions(name, codec, getter, GroupOptions{MaxEntries: maxEntries})
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// NewTypedGroupWithOptions 根据 opts 创建一个 TypedGroup 实例
+func NewTypedGroupWithOptions[T any](name string, codec Codec[T], getter TypedGetter[T], opts GroupOptions) (*TypedGroup[T], error) {
+	if codec == nil {
+		return nil, fmt.Errorf("nil codec")
+	}
+	if getter == nil {
+		return nil, fmt.Errorf("nil getter")
+	}
+	g, err := NewGroupWithOptions(name, typedGetter[T]{getter: getter, codec: codec}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedGroup[T]{group: g, codec: codec}, nil
+}
+
+// Group 返回底层的 Group，用于注册 Picker、查看统计信息等
+func (g *TypedGroup[T]) Group() *Group {
+	return g.group
+}
+
+// Get 获取 key 对应的对象
+func (g *TypedGroup[T]) Get(key string) (T, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 与 Get 相同，但 ctx 的超时和取消会传递到远程节点和数据源
+func (g *TypedGroup[T]) GetContext(ctx context.Context, key string) (T, error) {
+	view, err := g.group.GetContext(ctx, key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return g.decode(view)
+}
+
+// GetMany 批量获取 keys 对应的对象，返回的结果与 keys 一一对应
+func (g *TypedGroup[T]) GetMany(ctx context.Context, keys []string) []TypedResult[T] {
+	results := make([]TypedResult[T], len(keys))
+	for i, r := range g.group.GetMany(ctx, keys) {
+		if r.Err != nil {
+			results[i].Err = r.Err
+			continue
+		}
+		results[i].Value, results[i].Err = g.decode(r.Value)
+	}
+	return results
+}
+
+// Set 将 key 的值设置为 v，并写入 key 所属的节点
+func (g *TypedGroup[T]) Set(ctx context.Context, key string, v T, expire time.Time) error {
+	data, err := g.codec.Encode(v)
+	if err != nil {
+		return err
+	}
+	return g.group.Set(ctx, key, data, expire)
+}
+
+// Remove 从 key 所属的节点和当前节点删除 key 的缓存
+func (g *TypedGroup[T]) Remove(ctx context.Context, key string) error {
+	return g.group.Remove(ctx, key)
+}
+
+// decode 解码 view，view 来自缓存时复用并保存解码后的对象
+func (g *TypedGroup[T]) decode(view ByteView) (T, error) {
+	if view.d != nil {
+		if v, ok := view.d.load().(T); ok {
+			return v, nil
+		}
+	}
+	v, err := g.codec.Decode(view.ByteSlice())
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if view.d != nil {
+		view.d.store(v)
+	}
+	return v, nil
+}
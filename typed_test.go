@@ -0,0 +1,91 @@
+package pcache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "pcache/pcachepb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type user struct {
+	Name string
+	Age  int
+}
+
+// countingCodec 记录 Decode 的调用次数
+type countingCodec struct {
+	JSONCodec[user]
+	decodes atomic.Int64
+}
+
+func (c *countingCodec) Decode(data []byte) (user, error) {
+	c.decodes.Add(1)
+	return c.JSONCodec.Decode(data)
+}
+
+func TestTypedGroup(t *testing.T) {
+	codec := &countingCodec{}
+	g := NewTypedGroup[user]("typed-test", 0, codec, TypedGetterFunc[user](func(ctx context.Context, key string) (user, error) {
+		if key == "unknown" {
+			return user{}, ErrNotFound
+		}
+		return user{Name: key, Age: 18}, nil
+	}))
+
+	for i := 0; i < 3; i++ {
+		u, err := g.Get("Tom")
+		if err != nil || u != (user{Name: "Tom", Age: 18}) {
+			t.Fatalf("want Tom, but got %+v, %v", u, err)
+		}
+	}
+	if n := codec.decodes.Load(); n != 1 {
+		t.Fatalf("cached object should be decoded once, but got %d", n)
+	}
+	if _, err := g.Get("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, but got %v", err)
+	}
+
+	// Set 之后得到新的对象
+	if err := g.Set(context.Background(), "Tom", user{Name: "Tom", Age: 20}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	results := g.GetMany(context.Background(), []string{"Tom", "Jack"})
+	if results[0].Err != nil || results[0].Value.Age != 20 {
+		t.Fatalf("want updated Tom, but got %+v", results[0])
+	}
+	if results[1].Err != nil || results[1].Value.Name != "Jack" {
+		t.Fatalf("want Jack, but got %+v", results[1])
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	u := user{Name: "Tom", Age: 18}
+	for name, codec := range map[string]Codec[user]{
+		"json": JSONCodec[user]{},
+		"gob":  GobCodec[user]{},
+	} {
+		data, err := codec.Encode(u)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got, err := codec.Decode(data); err != nil || got != u {
+			t.Fatalf("%s: want %+v, but got %+v, %v", name, u, got, err)
+		}
+	}
+
+	codec := ProtoCodec[*pb.Item]{}
+	item := &pb.Item{Value: []byte("630"), Code: 5, Message: "not found"}
+	data, err := codec.Encode(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := codec.Decode(data)
+	if err != nil || !proto.Equal(got, item) {
+		t.Fatalf("proto: want %v, but got %v, %v", item, got, err)
+	}
+}
@@ -0,0 +1,57 @@
+package pcache
+
+import "sync"
+
+// versionSlots 是 keyVersions 中槽的数量
+const versionSlots = 256
+
+// keyVersions 记录 key 被 Set 和 Remove 修改的次数
+// 加载开始时记录 key 的版本，写入缓存时版本已经变化说明期间 key 被修改过，加载的结果已经过期，需要丢弃
+// key 按哈希分散到固定数量的槽中，不同的 key 可能共享同一个槽，此时只会多丢弃一些结果
+type keyVersions struct {
+	slots [versionSlots]versionSlot
+}
+
+type versionSlot struct {
+	mu sync.Mutex
+	v  uint64
+}
+
+// slot 返回 key 所在的槽，使用 FNV-1a 哈希
+func (kv *keyVersions) slot(key string) *versionSlot {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return &kv.slots[h%versionSlots]
+}
+
+// get 返回 key 当前的版本
+func (kv *keyVersions) get(key string) uint64 {
+	s := kv.slot(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.v
+}
+
+// update 增加 key 的版本并执行 fn，fn 执行期间版本不会被读取
+func (kv *keyVersions) update(key string, fn func()) {
+	s := kv.slot(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.v++
+	fn()
+}
+
+// apply 只在 key 的版本仍然是 ver 时执行 fn，返回 fn 是否被执行
+func (kv *keyVersions) apply(key string, ver uint64, fn func()) bool {
+	s := kv.slot(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.v != ver {
+		return false
+	}
+	fn()
+	return true
+}